@@ -26,6 +26,12 @@ type Assignment struct {
 	Value IExpression
 }
 
+type ConstDefinition struct {
+	Token token.Token
+	Left  *Identifier
+	Value IExpression
+}
+
 type StructFieldAssignment struct {
 	Token token.Token
 	Left  *StructFieldCall
@@ -93,6 +99,7 @@ type Function struct {
 	Arguments       []*VarAndType
 	ReturnType      string
 	StatementsBlock *StatementsBlock
+	Doc             string
 }
 
 type VarAndType struct {
@@ -114,10 +121,32 @@ type IfStatement struct {
 	ElseBranch     *StatementsBlock
 }
 
+type Assert struct {
+	Token         token.Token
+	Condition     IExpression
+	ConditionText string
+	Message       string
+}
+
+type TryStatement struct {
+	Token         token.Token
+	Body          *StatementsBlock
+	ErrVar        *Identifier
+	RecoverBranch *StatementsBlock
+}
+
+type IfExpression struct {
+	Token          token.Token
+	Condition      IExpression
+	PositiveBranch IExpression
+	ElseBranch     IExpression
+}
+
 type EnumDefinition struct {
 	Token    token.Token
 	Name     string
 	Elements []string
+	Doc      string
 }
 
 type EnumElementCall struct {
@@ -126,10 +155,18 @@ type EnumElementCall struct {
 	Element  *Identifier
 }
 
+type TypeDefinition struct {
+	Token      token.Token
+	Name       string
+	Underlying string
+	IsAlias    bool
+}
+
 type StructDefinition struct {
 	Token  token.Token
 	Name   string
-	Fields map[string]*VarAndType
+	Fields []*VarAndType
+	Doc    string
 }
 
 type Struct struct {
@@ -142,6 +179,7 @@ type StructFieldCall struct {
 	Token      token.Token
 	StructExpr IExpression
 	Field      *Identifier
+	IsSafe     bool
 }
 
 type Case struct {
@@ -158,6 +196,7 @@ type Switch struct {
 }
 
 func (node *Assignment) GetToken() token.Token            { return node.Token }
+func (node *ConstDefinition) GetToken() token.Token       { return node.Token }
 func (node *StructFieldAssignment) GetToken() token.Token { return node.Token }
 func (node *UnaryExpression) GetToken() token.Token       { return node.Token }
 func (node *BinExpression) GetToken() token.Token         { return node.Token }
@@ -172,7 +211,11 @@ func (node *Function) GetToken() token.Token              { return node.Token }
 func (node *VarAndType) GetToken() token.Token            { return node.Token }
 func (node *FunctionCall) GetToken() token.Token          { return node.Token }
 func (node *IfStatement) GetToken() token.Token           { return node.Token }
+func (node *IfExpression) GetToken() token.Token          { return node.Token }
+func (node *TryStatement) GetToken() token.Token          { return node.Token }
+func (node *Assert) GetToken() token.Token                { return node.Token }
 func (node *StructDefinition) GetToken() token.Token      { return node.Token }
+func (node *TypeDefinition) GetToken() token.Token        { return node.Token }
 func (node *Struct) GetToken() token.Token                { return node.Token }
 func (node *StructFieldCall) GetToken() token.Token       { return node.Token }
 func (node *EnumDefinition) GetToken() token.Token        { return node.Token }
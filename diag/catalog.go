@@ -0,0 +1,111 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Locale is a language of error messages
+type Locale string
+
+const (
+	LocaleEn Locale = "en"
+	LocaleRu Locale = "ru"
+)
+
+// DefaultLocale is used for error messages until error is localized with Localize
+const DefaultLocale = LocaleEn
+
+// message catalog: format strings of error messages by locale and error code.
+// Arguments of the error are passed to the format string in the same order for all locales
+var catalog = map[Locale]map[Code]string{
+	LocaleEn: messagesEn,
+	LocaleRu: messagesRu,
+}
+
+// hints are parts of messages that are not errors themselves
+const (
+	hintDidYouMean Code = "hint_did_you_mean"
+	hintOr         Code = "hint_or"
+)
+
+// texts of diagnostics output around error messages: positions, headers, stack traces
+const (
+	textPosition     Code = "text_position"
+	textLocation     Code = "text_location"
+	textHeader       Code = "text_header"
+	textPhaseLex     Code = "text_phase_lex"
+	textPhaseParse   Code = "text_phase_parse"
+	textPhaseRuntime Code = "text_phase_runtime"
+	// stack trace of error occurred inside function, see interpereter.StackTraceError
+	TextStackTrace Code = "text_stack_trace"
+	TextCalledAt   Code = "text_called_at"
+)
+
+var phaseNames = map[Phase]Code{
+	PhaseLex:     textPhaseLex,
+	PhaseParse:   textPhaseParse,
+	PhaseRuntime: textPhaseRuntime,
+}
+
+// phaseName returns localized name of the phase for error header
+func phaseName(locale Locale, phase Phase) string {
+	if code, ok := phaseNames[phase]; ok {
+		return Format(locale, code)
+	}
+	return string(phase)
+}
+
+// Format returns error message for the code in the locale.
+// Messages missing in the locale are taken from the default one
+func Format(locale Locale, code Code, args ...interface{}) string {
+	format, ok := catalog[locale][code]
+	if !ok {
+		format, ok = catalog[DefaultLocale][code]
+	}
+	if !ok {
+		return fmt.Sprintf("%s %v", code, args)
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Localize translates messages of all errors of the chain (or the list) to the locale.
+// Errors created without catalog, e.g. by host, are left as is
+func Localize(err error, locale Locale) {
+	var list List
+	if errors.As(err, &list) {
+		for _, e := range list {
+			Localize(e, locale)
+		}
+		return
+	}
+	var e *Error
+	for errors.As(err, &e) {
+		e.localize(locale)
+		err = e.Err
+	}
+}
+
+func (e *Error) localize(locale Locale) {
+	e.locale = locale
+	if !e.fromCatalog {
+		return
+	}
+	e.Message = Format(locale, e.Code, e.Args...)
+	if len(e.Suggestions) > 0 {
+		e.Message += ". " + Format(locale, hintDidYouMean, joinSuggestions(locale, e.Suggestions))
+	}
+}
+
+// joinSuggestions formats suggestions like "'a', 'b' or 'c'"
+func joinSuggestions(locale Locale, suggestions []string) string {
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = "'" + s + "'"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + Format(locale, hintOr) + quoted[len(quoted)-1]
+}
@@ -0,0 +1,51 @@
+package diag
+
+import (
+	"github.com/justclimber/marslang/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCatalogHasAllMessages(t *testing.T) {
+	for locale, messages := range catalog {
+		for code := range catalog[DefaultLocale] {
+			assert.Contains(t, messages, code, "locale %s", locale)
+		}
+		for code := range messages {
+			assert.Contains(t, catalog[DefaultLocale], code, "locale %s", locale)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "identifier not found: xelon", Format(LocaleEn, IdentifierNotFound, "xelon"))
+	assert.Equal(t, "идентификатор не найден: xelon", Format(LocaleRu, IdentifierNotFound, "xelon"))
+	assert.Equal(t, "identifier not found: xelon", Format(Locale("de"), IdentifierNotFound, "xelon"))
+	assert.Equal(t, "unknown_code [1 2]", Format(LocaleEn, Code("unknown_code"), 1, 2))
+}
+
+func TestLocalize(t *testing.T) {
+	e := New(PhaseRuntime, UnknownField, token.Token{Line: 3, Col: 4}, "point", "z").WithSuggestions([]string{"x", "y"})
+	assert.Equal(t, "Struct 'point' doesn't have field 'z'. Did you mean 'x' or 'y'?", e.Message)
+
+	wrapped := fmt.Errorf("wrapped: %w", e)
+	Localize(wrapped, LocaleRu)
+	assert.Equal(t, "У структуры 'point' нет поля 'z'. Возможно, имелось в виду 'x' или 'y'?", e.Message)
+	assert.Equal(t, []interface{}{"point", "z"}, e.Args)
+
+	list := List{New(PhaseParse, ConstNotAtTopLevel, token.Token{Line: 1, Col: 1}), e}
+	Localize(list, LocaleEn)
+	assert.Equal(t, "Const can be defined only at the top level of the program", list[0].Message)
+	assert.Equal(t, "Struct 'point' doesn't have field 'z'. Did you mean 'x' or 'y'?", list[1].Message)
+
+	hostErr := &Error{Code: BuiltinFailed, Message: "connection lost"}
+	Localize(hostErr, LocaleRu)
+	assert.Equal(t, "connection lost", hostErr.Message)
+
+	var target *Error
+	require.True(t, errors.As(wrapped, &target))
+}
@@ -0,0 +1,110 @@
+package diag
+
+// Code is a stable identifier of the error kind
+type Code string
+
+// Internal is an error of the interpreter itself, not of the program
+const Internal Code = "internal"
+
+// lexer errors
+const (
+	UnexpectedSymbol         Code = "unexpected_symbol"
+	SingleAmpersand          Code = "single_ampersand"
+	SinglePipe               Code = "single_pipe"
+	InvalidEncoding          Code = "invalid_encoding"
+	UnterminatedBlockComment Code = "unterminated_block_comment"
+	UnterminatedString       Code = "unterminated_string"
+	UnknownEscapeSequence    Code = "unknown_escape_sequence"
+	NumberWithoutDigits      Code = "number_without_digits"
+	InvalidDigit             Code = "invalid_digit"
+	ExponentWithoutDigits    Code = "exponent_without_digits"
+	InvalidDigitSeparator    Code = "invalid_digit_separator"
+)
+
+// parser errors
+const (
+	UnexpectedToken           Code = "unexpected_token"
+	UnexpectedTokenOneOf      Code = "unexpected_token_one_of"
+	UnexpectedStatementStart  Code = "unexpected_statement_start"
+	UnexpectedExpressionStart Code = "unexpected_expression_start"
+	UnexpectedBinaryOperator  Code = "unexpected_binary_operator"
+	IntLiteralOverflow        Code = "int_literal_overflow"
+	InvalidIntLiteral         Code = "invalid_int_literal"
+	FloatLiteralOutOfRange    Code = "float_literal_out_of_range"
+	InvalidFloatLiteral       Code = "invalid_float_literal"
+	EmptyStructDefinition     Code = "empty_struct_definition"
+	DuplicateStructField      Code = "duplicate_struct_field"
+	StructLiteralWithoutName  Code = "struct_literal_without_name"
+	ConstNotAtTopLevel        Code = "const_not_at_top_level"
+	NotConst                  Code = "not_const"
+	NotConstExpression        Code = "not_const_expression"
+	ConstUnknownOperator      Code = "const_unknown_operator"
+	ConstOperandTypesMismatch Code = "const_operand_types_mismatch"
+	ConstUnsupportedOperator  Code = "const_unsupported_operator"
+	ConstDivisionByZero       Code = "const_division_by_zero"
+	AssignmentToConst         Code = "assignment_to_const"
+	ConstRedefinition         Code = "const_redefinition"
+	NestingTooDeep            Code = "nesting_too_deep"
+)
+
+// runtime errors
+const (
+	UnexpectedNode           Code = "unexpected_node"
+	UnexpectedExpressionNode Code = "unexpected_expression_node"
+	AssignmentToBuiltin      Code = "assignment_to_builtin"
+	AssignmentToReadOnly     Code = "assignment_to_read_only"
+	AssignmentTypeMismatch   Code = "assignment_type_mismatch"
+	IdentifierNotFound       Code = "identifier_not_found"
+	OperandTypesMismatch     Code = "operand_types_mismatch"
+	UnsupportedOperator      Code = "unsupported_operator"
+	DivisionByZero           Code = "division_by_zero"
+	IntOverflow              Code = "int_overflow"
+	NotFiniteValue           Code = "not_finite_value"
+	UnsupportedUnaryOperator Code = "unsupported_unary_operator"
+	NotOperatorOnNonBool     Code = "not_operator_on_non_bool"
+	CoalesceOnNonEmptiable   Code = "coalesce_on_non_emptiable"
+	EmptierUnsupportedType   Code = "emptier_unsupported_type"
+	ConditionNotBool         Code = "condition_not_bool"
+	EmptyCondition           Code = "empty_condition"
+	CaseConditionNotBool     Code = "case_condition_not_bool"
+	EmptyCaseCondition       Code = "empty_case_condition"
+	EmptyBoolOperand         Code = "empty_bool_operand"
+	AssertConditionNotBool   Code = "assert_condition_not_bool"
+	AssertionFailed          Code = "assertion_failed"
+	// assertion with message, e.g. `assert x > 0, "x is negative"`
+	AssertionFailedWithMessage Code = "assertion_failed_with_message"
+	IfBranchesTypeMismatch     Code = "if_branches_type_mismatch"
+	NotAFunction               Code = "not_a_function"
+	ArgumentsCountMismatch     Code = "arguments_count_mismatch"
+	ArgumentTypeMismatch       Code = "argument_type_mismatch"
+	ReturnTypeMismatch         Code = "return_type_mismatch"
+	BuiltinArgumentsCount      Code = "builtin_arguments_count"
+	BuiltinArgumentType        Code = "builtin_argument_type"
+	BuiltinFailed              Code = "builtin_failed"
+	MaxCallDepthExceeded       Code = "max_call_depth_exceeded"
+	OutOfGas                   Code = "out_of_gas"
+	ExecutionCancelled         Code = "execution_cancelled"
+	DeadlineExceeded           Code = "deadline_exceeded"
+	ConversionArgumentsCount   Code = "conversion_arguments_count"
+	ConversionFailed           Code = "conversion_failed"
+	IndexOnNonArray            Code = "index_on_non_array"
+	IndexNotInt                Code = "index_not_int"
+	IndexOutOfBounds           Code = "index_out_of_bounds"
+	ArrayElementTypeMismatch   Code = "array_element_type_mismatch"
+	UndefinedStruct            Code = "undefined_struct"
+	StructRedefinition         Code = "struct_redefinition"
+	StructFieldsNotFilled      Code = "struct_fields_not_filled"
+	FieldAccessOnNonStruct     Code = "field_access_on_non_struct"
+	UnknownField               Code = "unknown_field"
+	FieldTypeMismatch          Code = "field_type_mismatch"
+	ReadOnlyField              Code = "read_only_field"
+	EmptyStructFieldRead       Code = "empty_struct_field_read"
+	EmptyStructFieldAssignment Code = "empty_struct_field_assignment"
+	FieldCantBeEmpty           Code = "field_cant_be_empty"
+	EnumExpected               Code = "enum_expected"
+	EnumRedefinition           Code = "enum_redefinition"
+	UnknownEnumElement         Code = "unknown_enum_element"
+	TypeRedefinition           Code = "type_redefinition"
+	UnknownType                Code = "unknown_type"
+	InvalidDistinctType        Code = "invalid_distinct_type"
+)
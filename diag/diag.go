@@ -0,0 +1,106 @@
+package diag
+
+import (
+	"github.com/justclimber/marslang/token"
+
+	"errors"
+	"strings"
+)
+
+// Phase is a stage of program processing where error occurred
+type Phase string
+
+const (
+	PhaseLex     Phase = "lex"
+	PhaseParse   Phase = "parse"
+	PhaseRuntime Phase = "runtime"
+)
+
+// Error is an error in the program with position in the source code.
+// Use errors.As to get it from errors returned by lexer, parser and interpreter
+type Error struct {
+	Phase Phase
+	// stable identifier of the error kind, doesn't depend on message text
+	Code Code
+	// message in DefaultLocale until error is localized with Localize
+	Message string
+	// parameters of the message, e.g. name of unknown identifier
+	Args []interface{}
+	Line int
+	Col  int
+	// position right after the erroneous part of the code
+	EndLine int
+	EndCol  int
+	// close names for unknown identifier, field, enum element etc.
+	Suggestions []string
+	// original error, e.g. returned by host builtin function
+	Err error
+	// message is made from the catalog, so it can be localized
+	fromCatalog bool
+	// locale of the message and the position text, empty means DefaultLocale
+	locale Locale
+}
+
+func (e *Error) Error() string {
+	return e.Message + "\n" + Format(e.Locale(), textPosition, e.Line, e.Col)
+}
+
+// Locale returns language the error is localized to
+func (e *Error) Locale() Locale {
+	if e.locale == "" {
+		return DefaultLocale
+	}
+	return e.locale
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// New creates error pointing to the token with message of the code from the catalog
+func New(phase Phase, code Code, t token.Token, args ...interface{}) *Error {
+	e := &Error{
+		Phase:       phase,
+		Code:        code,
+		Message:     Format(DefaultLocale, code, args...),
+		Args:        args,
+		Line:        t.Line,
+		Col:         t.Col,
+		EndLine:     t.EndLine,
+		EndCol:      t.EndCol,
+		fromCatalog: true,
+	}
+	if e.EndLine == 0 {
+		e.EndLine, e.EndCol = e.Line, e.Col
+	}
+	return e
+}
+
+// List is a list of errors, e.g. all errors found by parser
+type List []*Error
+
+func (l List) Error() string {
+	messages := make([]string, 0, len(l))
+	for _, e := range l {
+		messages = append(messages, e.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// As makes errors.As find the first matching error of the list
+func (l List) As(target interface{}) bool {
+	for _, e := range l {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is makes errors.Is look through all errors of the list
+func (l List) Is(target error) bool {
+	for _, e := range l {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
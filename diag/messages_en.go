@@ -0,0 +1,110 @@
+package diag
+
+var messagesEn = map[Code]string{
+	Internal: "internal error: %s",
+
+	UnexpectedSymbol:         "Unexpected symbol: %q",
+	SingleAmpersand:          "Unexpected one `&`. Did you mean '&&'?",
+	SinglePipe:               "Unexpected one `|`. Did you mean '||'?",
+	InvalidEncoding:          "Invalid UTF-8 encoding",
+	UnterminatedBlockComment: "Unterminated block comment",
+	UnterminatedString:       "Unterminated string literal",
+	UnknownEscapeSequence:    "Unknown escape sequence: '\\%c'",
+	NumberWithoutDigits:      "Number literal '%s' has no digits",
+	InvalidDigit:             "Invalid digit '%c' in number literal",
+	ExponentWithoutDigits:    "Exponent of number literal '%s' has no digits",
+	InvalidDigitSeparator:    "'_' must separate successive digits in number literal",
+
+	UnexpectedToken:           "expected token to be '%s', got '%s' instead",
+	UnexpectedTokenOneOf:      "expected token to be one of (%s), got '%s' instead",
+	UnexpectedStatementStart:  "Unexpected token for start of statement: %s",
+	UnexpectedExpressionStart: "no Unary parse function for %s found",
+	UnexpectedBinaryOperator:  "Unexpected next token for binary expression '%s'",
+	IntLiteralOverflow:        "integer literal %s overflows int",
+	InvalidIntLiteral:         "could not parse %q as integer",
+	FloatLiteralOutOfRange:    "float literal %s is out of range",
+	InvalidFloatLiteral:       "could not parse %q as float",
+	EmptyStructDefinition:     "Struct should contain at least 1 field",
+	DuplicateStructField:      "Struct field '%s' is already defined",
+	StructLiteralWithoutName:  "Struct operator should only on identifiers, but '%T'",
+	ConstNotAtTopLevel:        "Const can be defined only at the top level of the program",
+	NotConst:                  "'%s' is not a const and can't be used in const expression",
+	NotConstExpression:        "Const value should be a constant expression, '%T' given",
+	ConstUnknownOperator:      "unknown operator for const expression: %s%T",
+	ConstOperandTypesMismatch: "forbidden operation on different types in const expression: %T and %T",
+	ConstUnsupportedOperator:  "unsupported operator '%s' for '%s' in const expression",
+	ConstDivisionByZero:       "division by zero in const expression",
+	AssignmentToConst:         "Can't assign to const '%s'",
+	ConstRedefinition:         "Const '%s' is already defined",
+	NestingTooDeep:            "Expressions or blocks are nested too deep, max depth is %d",
+
+	UnexpectedNode:             "Unexpected node for statement: %T",
+	UnexpectedExpressionNode:   "Unexpected node for expression: %T",
+	AssignmentToBuiltin:        "Builtins are immutable",
+	AssignmentToReadOnly:       "Variable '%s' is read-only",
+	AssignmentTypeMismatch:     "type mismatch on assignment: var type is %s and value type is %s",
+	IdentifierNotFound:         "identifier not found: %s",
+	OperandTypesMismatch:       "forbidden operation on different types: %s and %s",
+	UnsupportedOperator:        "unsupported operator '%s' for type: '%s'",
+	DivisionByZero:             "division by zero",
+	IntOverflow:                "integer overflow: %s",
+	NotFiniteValue:             "Value assigned to '%s' is not a finite number: %s",
+	UnsupportedUnaryOperator:   "unknown operator: %s%s",
+	NotOperatorOnNonBool:       "Operator '!' could be applied only on bool, '%s' given",
+	CoalesceOnNonEmptiable:     "Operator '??' could be applied only on types that can be empty, '%s' given",
+	EmptierUnsupportedType:     "? is not supported on type: '%s'",
+	ConditionNotBool:           "Condition should be boolean type but %s in fact",
+	EmptyCondition:             "Condition is empty bool",
+	CaseConditionNotBool:       "Result of case condition should be 'boolean' but '%s' given",
+	EmptyCaseCondition:         "Result of case condition is empty bool",
+	EmptyBoolOperand:           "Operand of '%s' is empty bool",
+	AssertConditionNotBool:     "Assert condition should be boolean type but %s in fact",
+	AssertionFailed:            "assertion failed: %s",
+	AssertionFailedWithMessage: "assertion failed: %s (%s)",
+	IfBranchesTypeMismatch:     "Branches of if expression should have the same type but '%s' and '%s' given",
+	NotAFunction:               "not a function: %s",
+	ArgumentsCountMismatch:     "Function call arguments count mismatch: declared %d, but called %d",
+	ArgumentTypeMismatch:       "argument #%d type mismatch: expected '%s' by func declaration but called '%s'",
+	ReturnTypeMismatch:         "Return type mismatch: function declared as '%s' but in fact return '%s'",
+	BuiltinArgumentsCount:      "wrong number of arguments for '%s'. need %d, got %d",
+	BuiltinArgumentType:        "wrong type of argument #%d for '%s'. need %s, got %s",
+	BuiltinFailed:              "%s",
+	MaxCallDepthExceeded:       "max call depth %d exceeded, call chain: %s",
+	OutOfGas:                   "out of gas: used %d of %d",
+	ExecutionCancelled:         "execution cancelled",
+	DeadlineExceeded:           "execution deadline exceeded",
+	ConversionArgumentsCount:   "Conversion to '%s' requires exactly 1 argument but %d given",
+	ConversionFailed:           "Can't convert '%s' to '%s'",
+	IndexOnNonArray:            "Array access can be only on arrays but '%s' given",
+	IndexNotInt:                "Array access can be only by 'int' type but '%s' given",
+	IndexOutOfBounds:           "Array access out of bounds: '%d'",
+	ArrayElementTypeMismatch:   "Array element #%d should be type '%s' but '%s' given",
+	UndefinedStruct:            "Struct '%s' is not defined",
+	StructRedefinition:         "Struct '%s' is already defined",
+	StructFieldsNotFilled:      "Var of struct '%s' should have %d fields filled but in fact only %d",
+	FieldAccessOnNonStruct:     "Field access can be only on struct but '%s' given",
+	UnknownField:               "Struct '%s' doesn't have field '%s'",
+	FieldTypeMismatch:          "Field '%s' defined as '%s' but '%s' given",
+	ReadOnlyField:              "Field '%s' of struct '%s' is read-only",
+	EmptyStructFieldRead:       "Can't read field '%s' of empty struct '%s'. Check it with 'empty()' or use '?.'",
+	EmptyStructFieldAssignment: "Can't assign field '%s' of empty struct '%s'",
+	FieldCantBeEmpty:           "Field '%s' of type '%s' can't be empty",
+	EnumExpected:               "Expected enum, got '%s'",
+	EnumRedefinition:           "Enum '%s' is already defined",
+	UnknownEnumElement:         "Enum '%s' doesn't have element '%s'",
+	TypeRedefinition:           "Type '%s' is already defined",
+	UnknownType:                "Unknown type '%s'",
+	InvalidDistinctType:        "Distinct type can be based only on 'int' or 'float' but '%s' given",
+
+	hintDidYouMean: "Did you mean %s?",
+	hintOr:         " or ",
+
+	textPosition:     "line:%d, pos %d",
+	textLocation:     " --> line %d, pos %d",
+	textHeader:       "%s error [%s]",
+	TextStackTrace:   "stack trace:",
+	TextCalledAt:     "%s called at line:%d, pos %d",
+	textPhaseLex:     "lex",
+	textPhaseParse:   "parse",
+	textPhaseRuntime: "runtime",
+}
@@ -0,0 +1,110 @@
+package diag
+
+var messagesRu = map[Code]string{
+	Internal: "внутренняя ошибка: %s",
+
+	UnexpectedSymbol:         "Неожиданный символ: %q",
+	SingleAmpersand:          "Одиночный `&`. Возможно, имелось в виду '&&'?",
+	SinglePipe:               "Одиночный `|`. Возможно, имелось в виду '||'?",
+	InvalidEncoding:          "Некорректная кодировка UTF-8",
+	UnterminatedBlockComment: "Незакрытый блочный комментарий",
+	UnterminatedString:       "Незакрытый строковый литерал",
+	UnknownEscapeSequence:    "Неизвестная escape-последовательность: '\\%c'",
+	NumberWithoutDigits:      "В числовом литерале '%s' нет цифр",
+	InvalidDigit:             "Недопустимая цифра '%c' в числовом литерале",
+	ExponentWithoutDigits:    "В экспоненте числового литерала '%s' нет цифр",
+	InvalidDigitSeparator:    "'_' в числовом литерале может стоять только между цифрами",
+
+	UnexpectedToken:           "ожидался токен '%s', а получен '%s'",
+	UnexpectedTokenOneOf:      "ожидался один из токенов (%s), а получен '%s'",
+	UnexpectedStatementStart:  "Стейтмент не может начинаться с токена %s",
+	UnexpectedExpressionStart: "Выражение не может начинаться с токена %s",
+	UnexpectedBinaryOperator:  "Неожиданный токен '%s' в бинарном выражении",
+	IntLiteralOverflow:        "целочисленный литерал %s не помещается в int",
+	InvalidIntLiteral:         "не удалось разобрать %q как целое число",
+	FloatLiteralOutOfRange:    "литерал %s выходит за пределы float",
+	InvalidFloatLiteral:       "не удалось разобрать %q как float",
+	EmptyStructDefinition:     "Структура должна содержать хотя бы одно поле",
+	DuplicateStructField:      "Поле структуры '%s' уже объявлено",
+	StructLiteralWithoutName:  "Литерал структуры должен начинаться с её имени, а не с '%T'",
+	ConstNotAtTopLevel:        "Константу можно объявить только на верхнем уровне программы",
+	NotConst:                  "'%s' не константа и не может использоваться в константном выражении",
+	NotConstExpression:        "Значение константы должно быть константным выражением, а не '%T'",
+	ConstUnknownOperator:      "неизвестный оператор в константном выражении: %s%T",
+	ConstOperandTypesMismatch: "в константном выражении запрещены операции над разными типами: %T и %T",
+	ConstUnsupportedOperator:  "оператор '%s' не поддерживается для '%s' в константном выражении",
+	ConstDivisionByZero:       "деление на ноль в константном выражении",
+	AssignmentToConst:         "Нельзя присвоить значение константе '%s'",
+	ConstRedefinition:         "Константа '%s' уже объявлена",
+	NestingTooDeep:            "Слишком глубокая вложенность выражений или блоков, максимум %d",
+
+	UnexpectedNode:             "Неожиданный узел стейтмента: %T",
+	UnexpectedExpressionNode:   "Неожиданный узел выражения: %T",
+	AssignmentToBuiltin:        "Builtin функции нельзя переприсвоить",
+	AssignmentToReadOnly:       "Переменная '%s' доступна только для чтения",
+	AssignmentTypeMismatch:     "несовпадение типов при присваивании: тип переменной %s, а тип значения %s",
+	IdentifierNotFound:         "идентификатор не найден: %s",
+	OperandTypesMismatch:       "запрещена операция над разными типами: %s и %s",
+	UnsupportedOperator:        "оператор '%s' не поддерживается для типа '%s'",
+	DivisionByZero:             "деление на ноль",
+	IntOverflow:                "переполнение int: %s",
+	NotFiniteValue:             "Значение, присваиваемое '%s', не является конечным числом: %s",
+	UnsupportedUnaryOperator:   "неизвестный оператор: %s%s",
+	NotOperatorOnNonBool:       "Оператор '!' применим только к bool, а передан '%s'",
+	CoalesceOnNonEmptiable:     "Оператор '??' применим только к типам, которые могут быть пустыми, а передан '%s'",
+	EmptierUnsupportedType:     "? не поддерживается для типа '%s'",
+	ConditionNotBool:           "Условие должно иметь тип bool, а имеет %s",
+	EmptyCondition:             "Условие - пустой bool",
+	CaseConditionNotBool:       "Условие case должно иметь тип 'bool', а имеет '%s'",
+	EmptyCaseCondition:         "Условие case - пустой bool",
+	EmptyBoolOperand:           "Операнд '%s' - пустой bool",
+	AssertConditionNotBool:     "Условие assert должно иметь тип bool, а имеет %s",
+	AssertionFailed:            "проверка не прошла: %s",
+	AssertionFailedWithMessage: "проверка не прошла: %s (%s)",
+	IfBranchesTypeMismatch:     "Ветки if выражения должны иметь одинаковый тип, а имеют '%s' и '%s'",
+	NotAFunction:               "не функция: %s",
+	ArgumentsCountMismatch:     "Несовпадение количества аргументов: объявлено %d, а передано %d",
+	ArgumentTypeMismatch:       "несовпадение типа аргумента #%d: в объявлении функции '%s', а передан '%s'",
+	ReturnTypeMismatch:         "Несовпадение типа результата: функция объявлена как '%s', а возвращает '%s'",
+	BuiltinArgumentsCount:      "неверное количество аргументов для '%s': нужно %d, передано %d",
+	BuiltinArgumentType:        "неверный тип аргумента #%d для '%s': нужен %s, передан %s",
+	BuiltinFailed:              "%s",
+	MaxCallDepthExceeded:       "превышена максимальная глубина вызовов %d, цепочка вызовов: %s",
+	OutOfGas:                   "закончился газ: израсходовано %d из %d",
+	ExecutionCancelled:         "выполнение отменено",
+	DeadlineExceeded:           "превышено время выполнения",
+	ConversionArgumentsCount:   "Приведение к '%s' требует ровно 1 аргумент, а передано %d",
+	ConversionFailed:           "Нельзя привести '%s' к '%s'",
+	IndexOnNonArray:            "Обращение по индексу возможно только к массиву, а не к '%s'",
+	IndexNotInt:                "Индекс массива должен иметь тип 'int', а имеет '%s'",
+	IndexOutOfBounds:           "Индекс за пределами массива: '%d'",
+	ArrayElementTypeMismatch:   "Элемент массива #%d должен иметь тип '%s', а имеет '%s'",
+	UndefinedStruct:            "Структура '%s' не объявлена",
+	StructRedefinition:         "Структура '%s' уже объявлена",
+	StructFieldsNotFilled:      "У структуры '%s' должно быть заполнено полей: %d, а заполнено только %d",
+	FieldAccessOnNonStruct:     "Обращение к полю возможно только у структуры, а не у '%s'",
+	UnknownField:               "У структуры '%s' нет поля '%s'",
+	FieldTypeMismatch:          "Поле '%s' объявлено как '%s', а передан '%s'",
+	ReadOnlyField:              "Поле '%s' структуры '%s' доступно только для чтения",
+	EmptyStructFieldRead:       "Нельзя прочитать поле '%s' пустой структуры '%s'. Проверьте её через 'empty()' или используйте '?.'",
+	EmptyStructFieldAssignment: "Нельзя присвоить поле '%s' пустой структуры '%s'",
+	FieldCantBeEmpty:           "Поле '%s' типа '%s' не может быть пустым",
+	EnumExpected:               "Ожидался enum, а получен '%s'",
+	EnumRedefinition:           "Enum '%s' уже объявлен",
+	UnknownEnumElement:         "В enum '%s' нет элемента '%s'",
+	TypeRedefinition:           "Тип '%s' уже объявлен",
+	UnknownType:                "Неизвестный тип '%s'",
+	InvalidDistinctType:        "Собственный тип можно создать только на основе 'int' или 'float', а не '%s'",
+
+	hintDidYouMean: "Возможно, имелось в виду %s?",
+	hintOr:         " или ",
+
+	textPosition:     "строка:%d, поз %d",
+	textLocation:     " --> строка %d, поз %d",
+	textHeader:       "ошибка %s [%s]",
+	TextStackTrace:   "стэк вызовов:",
+	TextCalledAt:     "%s вызвана в строке:%d, поз %d",
+	textPhaseLex:     "лексера",
+	textPhaseParse:   "парсера",
+	textPhaseRuntime: "выполнения",
+}
@@ -0,0 +1,128 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultContextLines is a number of source lines shown before and after the erroneous line
+const DefaultContextLines = 1
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiError = "\x1b[1;31m"
+	ansiFaint = "\x1b[2;36m"
+)
+
+// Renderer formats errors for humans: message with excerpt of the source code
+// and the erroneous part underlined
+type Renderer struct {
+	// number of source lines shown before and after the erroneous line
+	ContextLines int
+	// use ANSI escape codes for terminal output
+	Color bool
+}
+
+func NewRenderer(color bool) *Renderer {
+	return &Renderer{ContextLines: DefaultContextLines, Color: color}
+}
+
+// Render formats error returned by lexer, parser or interpreter.
+// Every error of diag.List is rendered separately. Errors without position are rendered as is
+func (r *Renderer) Render(source string, err error) string {
+	var list List
+	if errors.As(err, &list) {
+		rendered := make([]string, 0, len(list))
+		for _, e := range list {
+			rendered = append(rendered, r.renderError(source, e, e))
+		}
+		return strings.Join(rendered, "\n\n")
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return r.renderError(source, e, err)
+	}
+	return err.Error()
+}
+
+// renderError renders e. Wrapping err may append its own details to the message, e.g. stack trace,
+// they are printed after the excerpt
+func (r *Renderer) renderError(source string, e *Error, err error) string {
+	var sb strings.Builder
+	locale := e.Locale()
+	sb.WriteString(r.paint(ansiError, Format(locale, textHeader, phaseName(locale, e.Phase), e.Code)))
+	sb.WriteString(": " + e.Message + "\n")
+
+	lines := strings.Split(source, "\n")
+	if e.Line > 0 && e.Line <= len(lines) {
+		sb.WriteString(r.paint(ansiFaint, Format(locale, textLocation, e.Line, e.Col)) + "\n")
+		r.writeExcerpt(&sb, lines, e)
+	}
+
+	if full := err.Error(); full != e.Error() && strings.HasPrefix(full, e.Error()) {
+		sb.WriteString(strings.TrimPrefix(strings.TrimPrefix(full, e.Error()), "\n") + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (r *Renderer) writeExcerpt(sb *strings.Builder, lines []string, e *Error) {
+	first := e.Line - r.ContextLines
+	if first < 1 {
+		first = 1
+	}
+	last := e.Line + r.ContextLines
+	if last > len(lines) {
+		last = len(lines)
+	}
+	// trailing newline of the source doesn't make a line
+	if last > e.Line && last == len(lines) && lines[last-1] == "" {
+		last--
+	}
+	gutterWidth := len(strconv.Itoa(last))
+
+	for i := first; i <= last; i++ {
+		line := strings.TrimSuffix(lines[i-1], "\r")
+		sb.WriteString(r.paint(ansiFaint, fmt.Sprintf("%*d | ", gutterWidth, i)))
+		sb.WriteString(line + "\n")
+		if i == e.Line {
+			sb.WriteString(r.paint(ansiFaint, strings.Repeat(" ", gutterWidth)+" | "))
+			sb.WriteString(r.marker(line, e) + "\n")
+		}
+	}
+}
+
+// marker returns line with the caret under the start of the erroneous part and underline till its end.
+// Tabs are kept in the padding so marker stays aligned with the code
+func (r *Renderer) marker(line string, e *Error) string {
+	runes := []rune(line)
+	var padding strings.Builder
+	for i := 0; i < e.Col-1; i++ {
+		if i < len(runes) && runes[i] == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+	}
+
+	width := 1
+	if e.EndLine == e.Line && e.EndCol > e.Col {
+		width = e.EndCol - e.Col
+	} else if e.EndLine > e.Line {
+		// multiline part is underlined till the end of the first line
+		width = utf8.RuneCountInString(line) - e.Col + 1
+	}
+	if width < 1 {
+		width = 1
+	}
+	return padding.String() + r.paint(ansiError, "^"+strings.Repeat("~", width-1))
+}
+
+func (r *Renderer) paint(color string, s string) string {
+	if !r.Color {
+		return s
+	}
+	return color + s + ansiReset
+}
@@ -0,0 +1,96 @@
+package diag
+
+import (
+	"github.com/justclimber/marslang/token"
+	"github.com/stretchr/testify/assert"
+
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	source := "a = 1\nb = a +  c\nd = 2\ne = 3\n"
+	err := &Error{
+		Phase:   PhaseRuntime,
+		Code:    IdentifierNotFound,
+		Message: "identifier not found: c",
+		Line:    2,
+		Col:     10,
+		EndLine: 2,
+		EndCol:  11,
+	}
+	expected := `runtime error [identifier_not_found]: identifier not found: c
+ --> line 2, pos 10
+1 | a = 1
+2 | b = a +  c
+  |          ^
+3 | d = 2`
+	assert.Equal(t, expected, NewRenderer(false).Render(source, err))
+}
+
+func TestRenderLocalized(t *testing.T) {
+	source := "a = 1\nb = a +  c\n"
+	err := New(PhaseRuntime, IdentifierNotFound, token.Token{Line: 2, Col: 10, EndLine: 2, EndCol: 11}, "c")
+	Localize(err, LocaleRu)
+	expected := `ошибка выполнения [identifier_not_found]: идентификатор не найден: c
+ --> строка 2, поз 10
+1 | a = 1
+2 | b = a +  c
+  |          ^`
+	assert.Equal(t, expected, NewRenderer(false).Render(source, err))
+	assert.Equal(t, "идентификатор не найден: c\nстрока:2, поз 10", err.Error())
+}
+
+func TestRenderRangeAndTabs(t *testing.T) {
+	source := "\tx = 12345 + y"
+	err := &Error{
+		Phase:   PhaseParse,
+		Code:    IntLiteralOverflow,
+		Message: "overflow",
+		Line:    1,
+		Col:     6,
+		EndLine: 1,
+		EndCol:  11,
+	}
+	expected := "parse error [int_literal_overflow]: overflow\n" +
+		" --> line 1, pos 6\n" +
+		"1 | \tx = 12345 + y\n" +
+		"  | \t    ^~~~~"
+	assert.Equal(t, expected, NewRenderer(false).Render(source, err))
+}
+
+func TestRenderList(t *testing.T) {
+	source := "a = @\nb = #\n"
+	err := List{
+		{Phase: PhaseLex, Code: UnexpectedSymbol, Message: "first", Line: 1, Col: 5},
+		{Phase: PhaseLex, Code: UnexpectedSymbol, Message: "second", Line: 2, Col: 5},
+	}
+	r := NewRenderer(false)
+	r.ContextLines = 0
+	expected := `lex error [unexpected_symbol]: first
+ --> line 1, pos 5
+1 | a = @
+  |     ^
+
+lex error [unexpected_symbol]: second
+ --> line 2, pos 5
+2 | b = #
+  |     ^`
+	assert.Equal(t, expected, r.Render(source, err))
+}
+
+func TestRenderWrappedAndColored(t *testing.T) {
+	source := "a = b\n"
+	err := &Error{Phase: PhaseRuntime, Code: IdentifierNotFound, Message: "not found", Line: 1, Col: 5}
+	wrapped := fmt.Errorf("%w\nstack trace: f", err)
+
+	rendered := NewRenderer(false).Render(source, wrapped)
+	assert.Contains(t, rendered, "  |     ^\nstack trace: f")
+
+	rendered = NewRenderer(true).Render(source, err)
+	assert.Contains(t, rendered, ansiError+"runtime error [identifier_not_found]"+ansiReset)
+
+	plain := errors.New("no position")
+	assert.Equal(t, "no position", NewRenderer(true).Render(source, plain))
+}
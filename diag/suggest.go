@@ -0,0 +1,94 @@
+package diag
+
+import (
+	"sort"
+	"strings"
+)
+
+// MaxSuggestions limits number of "did you mean" suggestions in the error
+const MaxSuggestions = 3
+
+// Suggest returns candidates close to the name by edit distance, the closest first.
+// Allowed distance grows with the name length: a third of it but at least 1
+func Suggest(name string, candidates []string) []string {
+	maxDistance := len([]rune(name)) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	distances := make(map[string]int)
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+		if _, seen := distances[c]; seen {
+			continue
+		}
+		if d := editDistance(strings.ToLower(name), strings.ToLower(c)); d <= maxDistance {
+			distances[c] = d
+		}
+	}
+
+	suggestions := make([]string, 0, len(distances))
+	for c := range distances {
+		suggestions = append(suggestions, c)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		di, dj := distances[suggestions[i]], distances[suggestions[j]]
+		if di != dj {
+			return di < dj
+		}
+		return suggestions[i] < suggestions[j]
+	})
+	if len(suggestions) > MaxSuggestions {
+		suggestions = suggestions[:MaxSuggestions]
+	}
+	return suggestions
+}
+
+// WithSuggestions adds suggestions to the error and "did you mean" hint to its message
+func (e *Error) WithSuggestions(suggestions []string) *Error {
+	if len(suggestions) == 0 {
+		return e
+	}
+	e.Suggestions = suggestions
+	e.Message += ". " + Format(DefaultLocale, hintDidYouMean, joinSuggestions(DefaultLocale, suggestions))
+	return e
+}
+
+// editDistance is Damerau-Levenshtein distance (optimal string alignment variant),
+// so swapped neighbour letters like in "xelno" count as one typo
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func min(first int, rest ...int) int {
+	m := first
+	for _, v := range rest {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
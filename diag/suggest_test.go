@@ -0,0 +1,36 @@
+package diag
+
+import (
+	"github.com/stretchr/testify/assert"
+
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		expected   []string
+	}{
+		{"xelno", []string{"xelon", "mech", "objects"}, []string{"xelon"}},
+		{"dist", []string{"dist", "list"}, []string{"list"}},
+		{"mehc", []string{"mech", "Mech", "much", "mech"}, []string{"Mech", "mech"}},
+		{"angleToRotat", []string{"angleToRotate", "angleTo", "angle"}, []string{"angleToRotate"}},
+		{"a", []string{"b", "c", "d", "e"}, []string{"b", "c", "d"}},
+		{"objects", []string{"mech", "commands"}, []string{}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Suggest(tt.name, tt.candidates), tt.name)
+	}
+}
+
+func TestWithSuggestions(t *testing.T) {
+	e := &Error{Message: "identifier not found: x"}
+	e.WithSuggestions([]string{"a", "b", "c"})
+	assert.Equal(t, "identifier not found: x. Did you mean 'a', 'b' or 'c'?", e.Message)
+
+	e = &Error{Message: "identifier not found: x"}
+	e.WithSuggestions(nil)
+	assert.Equal(t, "identifier not found: x", e.Message)
+	assert.Nil(t, e.Suggestions)
+}
@@ -0,0 +1,41 @@
+package interpereter
+
+import (
+	"github.com/justclimber/marslang/diag"
+
+	"log"
+)
+
+type AssertMode int
+
+const (
+	// AssertFatal aborts program execution on failed assertion
+	AssertFatal AssertMode = iota
+	// AssertLog reports failed assertion to the assert logger and continues execution
+	AssertLog
+	// AssertStrip skips assert statements completely, conditions are not executed
+	AssertStrip
+)
+
+type AssertLogger func(err *AssertionError)
+
+func defaultAssertLogger(err *AssertionError) {
+	log.Println(err.Error())
+}
+
+// AssertionError is failed assert statement. Condition is the source text of the asserted expression
+type AssertionError struct {
+	Condition string
+	Message   string
+	fatalError
+}
+
+func newAssertionError(condition, message string, line, col int) *AssertionError {
+	err := &AssertionError{Condition: condition, Message: message}
+	if message == "" {
+		err.fatalError = newFatalError(line, col, diag.AssertionFailed, condition)
+	} else {
+		err.fatalError = newFatalError(line, col, diag.AssertionFailedWithMessage, condition, message)
+	}
+	return err
+}
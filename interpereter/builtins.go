@@ -1,8 +1,13 @@
 package interpereter
 
 import (
+	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/object"
+	"github.com/justclimber/marslang/token"
 
+	"context"
+	"errors"
 	"fmt"
 	"math"
 )
@@ -25,7 +30,7 @@ func (e *ExecAstVisitor) setupBasicBuiltinFunctions() {
 		Name:       BuiltinPrint,
 		ArgTypes:   object.ArgTypes{"any"},
 		ReturnType: object.TypeVoid,
-		Fn: func(env *object.Environment, args []object.Object) (object.Object, error) {
+		Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
 			fmt.Println(args[0].Inspect())
 			return &object.Void{}, nil
 		},
@@ -34,26 +39,19 @@ func (e *ExecAstVisitor) setupBasicBuiltinFunctions() {
 		Name:       BuiltinEmpty,
 		ArgTypes:   object.ArgTypes{"any"},
 		ReturnType: object.TypeBool,
-		Fn: func(env *object.Environment, args []object.Object) (object.Object, error) {
-			switch arg := args[0].(type) {
-			case *object.Struct:
-				return nativeBooleanToBoolean(arg.Empty), nil
-			case *object.Integer:
-				return nativeBooleanToBoolean(arg.Empty), nil
-			case *object.Float:
-				return nativeBooleanToBoolean(arg.Empty), nil
-			case *object.Array:
-				return nativeBooleanToBoolean(arg.Empty), nil
-			default:
-				return nil, BuiltinFuncError("Type '%T' doesn't support emptiness", arg)
+		Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+			arg, ok := args[0].(object.Emptiable)
+			if !ok {
+				return nil, BuiltinFuncError("Type '%s' doesn't support emptiness", args[0].Type())
 			}
+			return nativeBooleanToBoolean(arg.IsEmpty()), nil
 		},
 	}
 	e.builtins[BuiltinLength] = &object.Builtin{
 		Name:       BuiltinLength,
 		ArgTypes:   object.ArgTypes{"array"},
 		ReturnType: object.TypeInt,
-		Fn: func(env *object.Environment, args []object.Object) (object.Object, error) {
+		Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
 			array := args[0].(*object.Array)
 			length := len(array.Elements)
 			return &object.Integer{Value: int64(length)}, nil
@@ -63,7 +61,7 @@ func (e *ExecAstVisitor) setupBasicBuiltinFunctions() {
 		Name:       BuiltinAbsInt,
 		ArgTypes:   object.ArgTypes{object.TypeInt},
 		ReturnType: object.TypeInt,
-		Fn: func(env *object.Environment, args []object.Object) (object.Object, error) {
+		Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
 			int := args[0].(*object.Integer).Value
 			return &object.Integer{Value: AbsInt64(int)}, nil
 		},
@@ -72,7 +70,7 @@ func (e *ExecAstVisitor) setupBasicBuiltinFunctions() {
 		Name:       BuiltinAbsFloat,
 		ArgTypes:   object.ArgTypes{object.TypeFloat},
 		ReturnType: object.TypeFloat,
-		Fn: func(env *object.Environment, args []object.Object) (object.Object, error) {
+		Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
 			float := args[0].(*object.Float).Value
 			return &object.Float{Value: math.Abs(float)}, nil
 		},
@@ -85,13 +83,12 @@ func (e *ExecAstVisitor) AddBuiltinFunctions(builtins map[string]*object.Builtin
 	}
 }
 
-func (e *ExecAstVisitor) checkArgs(builtin *object.Builtin, args []object.Object) error {
+func (e *ExecAstVisitor) checkArgs(node *ast.FunctionCall, builtin *object.Builtin, args []object.Object) error {
 	if builtin.ArgTypes == nil {
 		return nil
 	}
 	if len(builtin.ArgTypes) != len(args) {
-		return fmt.Errorf(
-			"wrong number of arguments for '%s'. need %d, got %d",
+		return runtimeError(node, diag.BuiltinArgumentsCount,
 			builtin.Name,
 			len(builtin.ArgTypes),
 			len(args),
@@ -102,17 +99,15 @@ func (e *ExecAstVisitor) checkArgs(builtin *object.Builtin, args []object.Object
 			continue
 		} else if argType == "array" {
 			if _, ok := args[i].(*object.Array); !ok {
-				return fmt.Errorf(
-					"wrong type of argument #%d for '%s'. need %s, got %T",
+				return runtimeError(node, diag.BuiltinArgumentType,
 					i+1,
 					builtin.Name,
 					argType,
-					args[i],
+					args[i].Type(),
 				)
 			}
 		} else if argType != string(args[i].Type()) {
-			return fmt.Errorf(
-				"wrong type of argument #%d for '%s'. need %s, got %s",
+			return runtimeError(node, diag.BuiltinArgumentType,
 				i+1,
 				builtin.Name,
 				argType,
@@ -123,7 +118,24 @@ func (e *ExecAstVisitor) checkArgs(builtin *object.Builtin, args []object.Object
 	return nil
 }
 
-// todo line and col
+// BuiltinFuncError creates recoverable error of builtin function.
+// Position of the builtin call is set by interpreter
 func BuiltinFuncError(format string, args ...interface{}) error {
-	return fmt.Errorf(format, args...)
+	return diag.New(diag.PhaseRuntime, diag.BuiltinFailed, token.Token{}, fmt.Sprintf(format, args...))
+}
+
+// positionBuiltinError sets position of the builtin call to the error returned by builtin function.
+// Errors created without BuiltinFuncError are wrapped, so the original error is still available with errors.As
+func positionBuiltinError(node *ast.FunctionCall, err error) error {
+	var diagErr *diag.Error
+	if !errors.As(err, &diagErr) {
+		diagErr := runtimeError(node, diag.BuiltinFailed, err.Error()).(*diag.Error)
+		diagErr.Err = err
+		return diagErr
+	}
+	if diagErr.Line == 0 {
+		t := node.GetToken()
+		diagErr.Line, diagErr.Col, diagErr.EndLine, diagErr.EndCol = t.Line, t.Col, t.EndLine, t.EndCol
+	}
+	return err
 }
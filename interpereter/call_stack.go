@@ -0,0 +1,148 @@
+package interpereter
+
+import (
+	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
+
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxCallDepth limits nesting of function calls if not set by SetMaxCallDepth
+const DefaultMaxCallDepth = 1000
+
+// MaxCallDepthLimit is the hard ceiling of call depth that host can't lift. Go stack overflow kills the process
+// and can't be recovered, a call with expressions of the maximal nesting takes about 100KB of it
+const MaxCallDepthLimit = 2000
+
+// StackFrame is a called function with position of the call
+type StackFrame struct {
+	FuncName string
+	Line     int
+	Col      int
+}
+
+// MaxCallDepthError is returned when nesting of function calls exceeds the limit, usually because of endless recursion.
+// It can't be caught by try/recover block
+type MaxCallDepthError struct {
+	MaxDepth int
+	// names of called functions from the outermost to the innermost one
+	CallChain []string
+	fatalError
+}
+
+func newMaxCallDepthError(maxDepth int, chain []string, frame StackFrame) *MaxCallDepthError {
+	return &MaxCallDepthError{
+		MaxDepth:   maxDepth,
+		CallChain:  chain,
+		fatalError: newFatalError(frame.Line, frame.Col, diag.MaxCallDepthExceeded, maxDepth, formatCallChain(chain)),
+	}
+}
+
+// StackTraceError wraps error occurred inside a function with the stack of calls led to it
+type StackTraceError struct {
+	Err error
+	// frames from the innermost call to the outermost one
+	Stack []StackFrame
+}
+
+func (e *StackTraceError) Error() string {
+	// stack trace is in the same language as the error itself
+	locale := diag.DefaultLocale
+	var diagErr *diag.Error
+	if errors.As(e.Err, &diagErr) {
+		locale = diagErr.Locale()
+	}
+	var sb strings.Builder
+	sb.WriteString(e.Err.Error())
+	sb.WriteString("\n" + diag.Format(locale, diag.TextStackTrace))
+	for i := 0; i < len(e.Stack); {
+		// recursive calls from the same place are collapsed
+		j := i + 1
+		for j < len(e.Stack) && e.Stack[j] == e.Stack[i] {
+			j++
+		}
+		frame := e.Stack[i]
+		sb.WriteString("\n   " + diag.Format(locale, diag.TextCalledAt, frame.FuncName, frame.Line, frame.Col))
+		if j-i > 1 {
+			sb.WriteString(fmt.Sprintf(" (x%d)", j-i))
+		}
+		i = j
+	}
+	return sb.String()
+}
+
+func (e *StackTraceError) Unwrap() error { return e.Err }
+
+// formatCallChain joins function names and collapses repeated calls like in recursion: "main -> fact x999"
+func formatCallChain(chain []string) string {
+	var parts []string
+	for i := 0; i < len(chain); {
+		j := i + 1
+		for j < len(chain) && chain[j] == chain[i] {
+			j++
+		}
+		if j-i > 1 {
+			parts = append(parts, fmt.Sprintf("%s x%d", chain[i], j-i))
+		} else {
+			parts = append(parts, chain[i])
+		}
+		i = j
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// callName returns name of called function for the call stack.
+// Name of the variable function was bound to has priority over the name it is called by
+func callName(node *ast.FunctionCall, boundName string) string {
+	if boundName != "" {
+		return boundName
+	}
+	switch fn := node.Function.(type) {
+	case *ast.Identifier:
+		return fn.Value
+	case *ast.StructFieldCall:
+		return fn.Field.Value
+	default:
+		return "anonymous fn"
+	}
+}
+
+func newStackFrame(node *ast.FunctionCall, boundName string) StackFrame {
+	t := node.GetToken()
+	return StackFrame{FuncName: callName(node, boundName), Line: t.Line, Col: t.Col}
+}
+
+func (e *ExecAstVisitor) enterCall(frame StackFrame) error {
+	if len(e.callStack) >= e.maxCallDepth {
+		chain := make([]string, 0, len(e.callStack)+1)
+		for _, f := range e.callStack {
+			chain = append(chain, f.FuncName)
+		}
+		return newMaxCallDepthError(e.maxCallDepth, append(chain, frame.FuncName), frame)
+	}
+	e.callStack = append(e.callStack, frame)
+	return nil
+}
+
+// leaveCall pops current frame. Error occurred inside the call gets stack trace if it doesn't have one yet
+func (e *ExecAstVisitor) leaveCall(err error) error {
+	if err != nil {
+		var traced *StackTraceError
+		if !errors.As(err, &traced) {
+			err = &StackTraceError{Err: err, Stack: e.stackTrace()}
+		}
+	}
+	e.callStack = e.callStack[:len(e.callStack)-1]
+	return err
+}
+
+// stackTrace returns frames of the current call stack from the innermost call to the outermost one
+func (e *ExecAstVisitor) stackTrace() []StackFrame {
+	stack := make([]StackFrame, 0, len(e.callStack))
+	for i := len(e.callStack) - 1; i >= 0; i-- {
+		stack = append(stack, e.callStack[i])
+	}
+	return stack
+}
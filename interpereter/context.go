@@ -0,0 +1,37 @@
+package interpereter
+
+import (
+	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
+
+	"context"
+	"errors"
+)
+
+// CancelledError aborts execution when context passed to ExecAstContext is cancelled or its deadline passes.
+// Err is the context error, it is wrapped by the diag.Error, so errors.Is(err, context.DeadlineExceeded)
+// tells timeout from cancellation
+type CancelledError struct {
+	Err error
+	fatalError
+}
+
+func newCancelledError(ctxErr error, line, col int) *CancelledError {
+	code := diag.ExecutionCancelled
+	if errors.Is(ctxErr, context.DeadlineExceeded) {
+		code = diag.DeadlineExceeded
+	}
+	err := &CancelledError{Err: ctxErr, fatalError: newFatalError(line, col, code)}
+	err.diagErr.Err = ctxErr
+	return err
+}
+
+// checkContext aborts execution if the context is done. It is checked before every statement
+// and function call
+func (e *ExecAstVisitor) checkContext(node ast.INode) error {
+	if err := e.ctx.Err(); err != nil {
+		t := node.GetToken()
+		return newCancelledError(err, t.Line, t.Col)
+	}
+	return nil
+}
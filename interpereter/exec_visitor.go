@@ -2,13 +2,31 @@ package interpereter
 
 import (
 	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/object"
 	"github.com/justclimber/marslang/token"
+
+	"context"
+	"errors"
+	"fmt"
+	"math"
 )
 
 type ExecAstVisitor struct {
-	execCallback ExecCallback
-	builtins     map[string]*object.Builtin
+	execCallback  ExecCallback
+	builtins      map[string]*object.Builtin
+	assertMode    AssertMode
+	assertLogger  AssertLogger
+	maxCallDepth  int
+	callStack     []StackFrame
+	locale        diag.Locale
+	numericPolicy NumericPolicy
+	gasLimit      int64
+	gasUsed       int64
+	gasCosts      GasCosts
+	ctx           context.Context
+	// node executed last, for position of internal errors
+	currNode ast.INode
 }
 
 const (
@@ -33,6 +51,11 @@ const (
 	FunctionCall
 	EnumElementCall
 	Builtin
+	ConstDefinition
+	IfExpr
+	TryStmt
+	AssertStmt
+	TypeConversion
 )
 
 type OperationType int
@@ -48,6 +71,12 @@ func NewExecAstVisitor() *ExecAstVisitor {
 	e := &ExecAstVisitor{
 		execCallback: func(operation Operation) {},
 		builtins:     make(map[string]*object.Builtin),
+		assertMode:   AssertFatal,
+		assertLogger: defaultAssertLogger,
+		maxCallDepth: DefaultMaxCallDepth,
+		locale:       diag.DefaultLocale,
+		gasCosts:     DefaultGasCosts(),
+		ctx:          context.Background(),
 	}
 	e.setupBasicBuiltinFunctions()
 	return e
@@ -57,20 +86,83 @@ func (e *ExecAstVisitor) SetExecCallback(callback ExecCallback) {
 	e.execCallback = callback
 }
 
+// SetLocale sets language of error messages returned by ExecAst and seen by the program in recover block
+func (e *ExecAstVisitor) SetLocale(locale diag.Locale) {
+	e.locale = locale
+}
+
+// SetAssertMode configures how failed assertions are handled: abort execution, log or don't check at all
+func (e *ExecAstVisitor) SetAssertMode(mode AssertMode) {
+	e.assertMode = mode
+}
+
+func (e *ExecAstVisitor) SetAssertLogger(logger AssertLogger) {
+	e.assertLogger = logger
+}
+
+// SetMaxCallDepth limits nesting of function calls. Exceeding it aborts execution with MaxCallDepthError.
+// 0 or less means no limit of the host like in SetGasLimit, but depth is never greater than MaxCallDepthLimit
+func (e *ExecAstVisitor) SetMaxCallDepth(depth int) {
+	if depth <= 0 || depth > MaxCallDepthLimit {
+		depth = MaxCallDepthLimit
+	}
+	e.maxCallDepth = depth
+}
+
+// ExecAst executes the program. Panic during execution (e.g. in host builtin) doesn't crash the host,
+// it is returned as diag.Internal error pointing to the last executed node
 func (e *ExecAstVisitor) ExecAst(ast *ast.StatementsBlock, env *object.Environment) error {
-	_, err := e.execStatementsBlock(ast, env)
-	if err != nil {
-		return err
+	return e.ExecAstContext(context.Background(), ast, env)
+}
+
+// ExecAstContext executes the program like ExecAst, but stops with CancelledError when ctx is done.
+// Context is passed to builtin functions, so long running builtins can honor it too
+func (e *ExecAstVisitor) ExecAstContext(
+	ctx context.Context,
+	ast *ast.StatementsBlock,
+	env *object.Environment,
+) (err error) {
+	e.callStack = e.callStack[:0]
+	e.currNode = nil
+	e.gasUsed = 0
+	e.ctx = ctx
+	defer func() {
+		e.ctx = context.Background()
+		if r := recover(); r != nil {
+			err = e.internalError(r)
+		}
+		if err != nil {
+			diag.Localize(err, e.locale)
+		}
+	}()
+	_, err = e.execStatementsBlock(ast, env)
+	return err
+}
+
+func (e *ExecAstVisitor) internalError(panicValue interface{}) error {
+	var t token.Token
+	if e.currNode != nil {
+		t = e.currNode.GetToken()
+	}
+	err := diag.New(diag.PhaseRuntime, diag.Internal, t, fmt.Sprint(panicValue))
+	if len(e.callStack) > 0 {
+		return &StackTraceError{Err: err, Stack: e.stackTrace()}
 	}
-	return nil
+	return err
 }
 
 func (e *ExecAstVisitor) execStatementsBlock(node *ast.StatementsBlock, env *object.Environment) (object.Object, error) {
 	for _, statement := range node.Statements {
+		if err := e.checkContext(statement); err != nil {
+			return nil, err
+		}
 		result, err := e.execStatement(statement, env)
 		if err != nil {
 			return nil, err
 		}
+		if err = e.checkGas(statement); err != nil {
+			return nil, err
+		}
 		if returnStmt, ok := result.(*object.ReturnValue); ok {
 			return returnStmt, nil
 		}
@@ -81,9 +173,12 @@ func (e *ExecAstVisitor) execStatementsBlock(node *ast.StatementsBlock, env *obj
 }
 
 func (e *ExecAstVisitor) execStatement(node ast.IStatement, env *object.Environment) (object.Object, error) {
+	e.currNode = node
 	switch astNode := node.(type) {
 	case *ast.Assignment:
 		return e.execAssignment(astNode, env)
+	case *ast.ConstDefinition:
+		return e.execConstDefinition(astNode, env)
 	case *ast.StructFieldAssignment:
 		return e.execStructFieldAssignment(astNode, env)
 	case *ast.Return:
@@ -92,6 +187,10 @@ func (e *ExecAstVisitor) execStatement(node ast.IStatement, env *object.Environm
 		return e.execIfStatement(astNode, env)
 	case *ast.Switch:
 		return e.execSwitch(astNode, env)
+	case *ast.TryStatement:
+		return e.execTryStatement(astNode, env)
+	case *ast.Assert:
+		return e.execAssert(astNode, env)
 	case *ast.FunctionCall:
 		return e.execFunctionCall(astNode, env)
 	case *ast.StructDefinition:
@@ -104,12 +203,18 @@ func (e *ExecAstVisitor) execStatement(node ast.IStatement, env *object.Environm
 			return nil, err
 		}
 		return nil, nil
+	case *ast.TypeDefinition:
+		if err := registerTypeDefinition(astNode, env); err != nil {
+			return nil, err
+		}
+		return nil, nil
 	default:
-		return nil, runtimeError(node, "Unexpected node for statement: %T", node)
+		return nil, runtimeError(node, diag.UnexpectedNode, node)
 	}
 }
 
 func (e *ExecAstVisitor) execExpression(node ast.IExpression, env *object.Environment) (object.Object, error) {
+	e.currNode = node
 	switch astNode := node.(type) {
 	case *ast.UnaryExpression:
 		return e.execUnaryExpression(astNode, env)
@@ -139,28 +244,64 @@ func (e *ExecAstVisitor) execExpression(node ast.IExpression, env *object.Enviro
 		return e.execFunction(astNode, env)
 	case *ast.FunctionCall:
 		return e.execFunctionCall(astNode, env)
+	case *ast.IfExpression:
+		return e.execIfExpression(astNode, env)
 	default:
-		return nil, runtimeError(node, "Unexpected node for expression: %T", node)
+		return nil, runtimeError(node, diag.UnexpectedExpressionNode, node)
 	}
 }
 
 func (e *ExecAstVisitor) execAssignment(node *ast.Assignment, env *object.Environment) (object.Object, error) {
 	varName := node.Left.Value
 	if _, exists := e.builtins[varName]; exists {
-		return nil, runtimeError(node.Left, "Builtins are immutable")
+		return nil, runtimeError(node.Left, diag.AssignmentToBuiltin)
+	}
+	if env.IsConst(varName) {
+		return nil, runtimeError(node.Left, diag.AssignmentToConst, varName)
 	}
-	e.execCallback(Operation{Type: Assignment})
+	if env.IsReadOnly(varName) {
+		return nil, runtimeError(node.Left, diag.AssignmentToReadOnly, varName)
+	}
+	e.operation(Operation{Type: Assignment})
 	value, err := e.execExpression(node.Value, env)
 	if err != nil {
 		return nil, err
 	}
 
 	if oldVar, isVarExist := env.Get(varName); isVarExist && oldVar.Type() != value.Type() {
-		return nil, runtimeError(node.Value, "type mismatch on assignment: var type is %s and value type is %s",
-			oldVar.Type(), value.Type())
+		return nil, runtimeError(node.Value, diag.AssignmentTypeMismatch, oldVar.Type(), value.Type())
+	}
+	if err = e.checkFinite(node.Value, varName, value); err != nil {
+		return nil, err
+	}
+
+	// function gets name of the first variable it is bound to, to be shown in stack traces
+	if fn, ok := value.(*object.Function); ok && fn.Name == "" {
+		fn.Name = varName
 	}
 
-	env.Set(varName, value)
+	env.Assign(varName, value)
+	return value, nil
+}
+
+func (e *ExecAstVisitor) execConstDefinition(node *ast.ConstDefinition, env *object.Environment) (object.Object, error) {
+	constName := node.Left.Value
+	if _, exists := e.builtins[constName]; exists {
+		return nil, runtimeError(node.Left, diag.AssignmentToBuiltin)
+	}
+	if _, exists := env.Get(constName); exists {
+		return nil, runtimeError(node.Left, diag.ConstRedefinition, constName)
+	}
+	e.operation(Operation{Type: ConstDefinition})
+	value, err := e.execExpression(node.Value, env)
+	if err != nil {
+		return nil, err
+	}
+	if err = e.checkFinite(node.Value, constName, value); err != nil {
+		return nil, err
+	}
+
+	env.SetConst(constName, value)
 	return value, nil
 }
 
@@ -168,7 +309,7 @@ func (e *ExecAstVisitor) execStructFieldAssignment(
 	node *ast.StructFieldAssignment,
 	env *object.Environment,
 ) (object.Object, error) {
-	e.execCallback(Operation{Type: StructFieldAssignment})
+	e.operation(Operation{Type: StructFieldAssignment})
 	value, err := e.execExpression(node.Value, env)
 	if err != nil {
 		return nil, err
@@ -181,19 +322,31 @@ func (e *ExecAstVisitor) execStructFieldAssignment(
 
 	structObj, ok := left.(*object.Struct)
 	if !ok {
-		return nil, runtimeError(node, "Field access can be only on struct but '%s' given", left.Type())
+		return nil, runtimeError(node, diag.FieldAccessOnNonStruct, left.Type())
+	}
+	if structObj.Empty {
+		return nil, runtimeError(node, diag.EmptyStructFieldAssignment, node.Left.Field.Value, structObj.Definition.Name)
 	}
 
 	if _, ok = structObj.Fields[node.Left.Field.Value]; !ok {
-		return nil, runtimeError(node,
-			"Struct '%s' doesn't have field '%s'", structObj.Definition.Name, node.Left.Field.Value)
+		return nil, unknownNameError(node, diag.UnknownField, node.Left.Field.Value, structObj.Definition.FieldNames(),
+			structObj.Definition.Name, node.Left.Field.Value)
+	}
+	if structObj.IsFieldReadOnly(node.Left.Field.Value) {
+		return nil, runtimeError(node, diag.ReadOnlyField, node.Left.Field.Value, structObj.Definition.Name)
+	}
+	if fieldType := structObj.Definition.Fields[node.Left.Field.Value]; fieldType != string(value.Type()) {
+		return nil, runtimeError(node, diag.FieldTypeMismatch, node.Left.Field.Value, fieldType, value.Type())
+	}
+	if err = e.checkFinite(node.Value, node.Left.Field.Value, value); err != nil {
+		return nil, err
 	}
 	structObj.Fields[node.Left.Field.Value] = value
 	return value, nil
 }
 
 func (e *ExecAstVisitor) execUnaryExpression(node *ast.UnaryExpression, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: Unary})
+	e.operation(Operation{Type: Unary})
 	right, err := e.execExpression(node.Right, env)
 	if err != nil {
 		return nil, err
@@ -202,52 +355,47 @@ func (e *ExecAstVisitor) execUnaryExpression(node *ast.UnaryExpression, env *obj
 	case token.Not:
 		boolObj, ok := right.(*object.Boolean)
 		if !ok {
-			return nil, runtimeError(node, "Operator '!' could be applied only on bool, '%s' given", right.Type())
+			return nil, runtimeError(node, diag.NotOperatorOnNonBool, right.Type())
+		}
+		if boolObj.Empty {
+			return nil, runtimeError(node, diag.EmptyBoolOperand, node.Operator)
 		}
 		return nativeBooleanToBoolean(!boolObj.Value), nil
 	case token.Minus:
-		switch right.Type() {
-		case object.TypeInt:
-			value := right.(*object.Integer).Value
-			return &object.Integer{Value: -value}, nil
-		case object.TypeFloat:
-			value := right.(*object.Float).Value
-			return &object.Float{Value: -value}, nil
+		switch value := right.(type) {
+		case *object.Integer:
+			if value.Value == math.MinInt64 && e.numericPolicy.CheckIntOverflow {
+				return nil, runtimeError(node, diag.IntOverflow, fmt.Sprintf("-(%d)", value.Value))
+			}
+			return &object.Integer{Named: value.Named, Value: -value.Value}, nil
+		case *object.Float:
+			return &object.Float{Named: value.Named, Value: -value.Value}, nil
 		default:
-			return nil, runtimeError(node, "unknown operator: -%s", right.Type())
+			return nil, runtimeError(node, diag.UnsupportedUnaryOperator, node.Operator, right.Type())
 		}
 	default:
-		return nil, runtimeError(node, "unknown operator: %s%s", node.Operator, right.Type())
+		return nil, runtimeError(node, diag.UnsupportedUnaryOperator, node.Operator, right.Type())
 	}
 }
 
 func (e *ExecAstVisitor) execEmptierExpression(node *ast.EmptierExpression, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: Question})
+	e.operation(Operation{Type: Question})
+	varType := node.Type
 	if node.IsArray {
-		if node.Type == object.TypeInt || node.Type == object.TypeFloat {
-			return &object.Array{Emptier: object.Emptier{Empty: true}, ElementsType: node.Type}, nil
-		} else if _, ok := env.GetStructDefinition(node.Type); ok {
-			return &object.Array{Emptier: object.Emptier{Empty: true}, ElementsType: node.Type}, nil
-		} else {
-			return nil, runtimeError(node, "? is not supported on type: '%s[]'", node.Type)
-		}
-	} else if node.Type == object.TypeInt {
-		return &object.Integer{Emptier: object.Emptier{Empty: true}}, nil
-	} else if node.Type == object.TypeFloat {
-		return &object.Float{Emptier: object.Emptier{Empty: true}}, nil
-	} else if def, ok := env.GetStructDefinition(node.Type); ok {
-		return &object.Struct{
-			Emptier:    object.Emptier{Empty: true},
-			Definition: def,
-			Fields:     make(map[string]object.Object),
-		}, nil
-	} else {
-		return nil, runtimeError(node, "? is not supported on type: '%s'", node.Type)
+		varType = "[]" + varType
 	}
+	emptyValue, ok := createEmptyValue(varType, env)
+	if !ok {
+		return nil, runtimeError(node, diag.EmptierUnsupportedType, varType)
+	}
+	return emptyValue, nil
 }
 
 func (e *ExecAstVisitor) execBinExpression(node *ast.BinExpression, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: BinExpr})
+	e.operation(Operation{Type: BinExpr})
+	if node.Operator == token.NullCoalesce {
+		return e.execNullCoalesce(node, env)
+	}
 	left, err := e.execExpression(node.Left, env)
 	if err != nil {
 		return nil, err
@@ -258,21 +406,44 @@ func (e *ExecAstVisitor) execBinExpression(node *ast.BinExpression, env *object.
 	}
 
 	if left.Type() != right.Type() {
-		return nil, runtimeError(node, "forbidden operation on different types: %s and %s",
-			left.Type(), right.Type())
+		return nil, runtimeError(node, diag.OperandTypesMismatch, left.Type(), right.Type())
 	}
 
-	result, err := execScalarBinOperation(left, right, node.Operator)
+	result, err := execScalarBinOperation(left, right, node, e.numericPolicy)
 	return result, err
 }
 
+func (e *ExecAstVisitor) execNullCoalesce(node *ast.BinExpression, env *object.Environment) (object.Object, error) {
+	left, err := e.execExpression(node.Left, env)
+	if err != nil {
+		return nil, err
+	}
+	emptiable, ok := left.(object.Emptiable)
+	if !ok {
+		return nil, runtimeError(node, diag.CoalesceOnNonEmptiable, left.Type())
+	}
+	if !emptiable.IsEmpty() {
+		return left, nil
+	}
+
+	right, err := e.execExpression(node.Right, env)
+	if err != nil {
+		return nil, err
+	}
+	if left.Type() != right.Type() {
+		return nil, runtimeError(node, diag.OperandTypesMismatch, left.Type(), right.Type())
+	}
+	return right, nil
+}
+
 func (e *ExecAstVisitor) execIdentifier(node *ast.Identifier, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: Identifier})
+	e.operation(Operation{Type: Identifier})
 	if builtin, ok := e.builtins[node.Value]; ok {
 		return builtin, nil
 	}
 
-	if ed, ok := env.GetEnumDefinition(node.Value); ok {
+	// enum may be referred by its alias
+	if ed, ok := env.GetEnumDefinition(env.ResolveType(node.Value)); ok {
 		return &object.Enum{Definition: ed}, nil
 	}
 
@@ -280,17 +451,21 @@ func (e *ExecAstVisitor) execIdentifier(node *ast.Identifier, env *object.Enviro
 		return val, nil
 	}
 
-	return nil, runtimeError(node, "identifier not found: "+node.Value)
+	candidates := env.Names()
+	for name := range e.builtins {
+		candidates = append(candidates, name)
+	}
+	return nil, unknownNameError(node, diag.IdentifierNotFound, node.Value, candidates, node.Value)
 }
 
 func (e *ExecAstVisitor) execReturn(node *ast.Return, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: Return})
+	e.operation(Operation{Type: Return})
 	value, err := e.execExpression(node.ReturnValue, env)
 	return &object.ReturnValue{Value: value}, err
 }
 
 func (e *ExecAstVisitor) execFunction(node *ast.Function, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: Function})
+	e.operation(Operation{Type: Function})
 	return &object.Function{
 		Arguments:  node.Arguments,
 		Statements: node.StatementsBlock,
@@ -300,7 +475,16 @@ func (e *ExecAstVisitor) execFunction(node *ast.Function, env *object.Environmen
 }
 
 func (e *ExecAstVisitor) execFunctionCall(node *ast.FunctionCall, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: FunctionCall})
+	if ident, ok := node.Function.(*ast.Identifier); ok && isConversionType(ident.Value, env) {
+		return e.execTypeConversion(node, ident.Value, env)
+	}
+	e.operation(Operation{Type: FunctionCall})
+	if err := e.checkGas(node); err != nil {
+		return nil, err
+	}
+	if err := e.checkContext(node); err != nil {
+		return nil, err
+	}
 	functionObj, err := e.execExpression(node.Function, env)
 	if err != nil {
 		return nil, err
@@ -313,15 +497,18 @@ func (e *ExecAstVisitor) execFunctionCall(node *ast.FunctionCall, env *object.En
 
 	switch fn := functionObj.(type) {
 	case *object.Function:
-		err = functionCallArgumentsCheck(node, fn.Arguments, args)
+		err = functionCallArgumentsCheck(node, fn.Arguments, args, fn.Env)
 		if err != nil {
 			return nil, err
 		}
 
 		// todo: what is fn.Env?
 		functionEnv := transferArgsToNewEnv(fn, args)
+		if err = e.enterCall(newStackFrame(node, fn.Name)); err != nil {
+			return nil, err
+		}
 		result, err := e.execStatementsBlock(fn.Statements, functionEnv)
-		if err != nil {
+		if err = e.leaveCall(err); err != nil {
 			return nil, err
 		}
 
@@ -331,19 +518,30 @@ func (e *ExecAstVisitor) execFunctionCall(node *ast.FunctionCall, env *object.En
 			result = result.(*object.ReturnValue).Value
 		}
 
-		if err = functionReturnTypeCheck(node, result, fn.ReturnType); err != nil {
+		if err = functionReturnTypeCheck(node, result, fn.Env.ResolveType(fn.ReturnType)); err != nil {
 			return nil, err
 		}
 
 		return result, nil
 
 	case *object.Builtin:
-		e.execCallback(Operation{Type: Builtin, FuncName: fn.Name})
-		if err := e.checkArgs(fn, args); err != nil {
+		e.operation(Operation{Type: Builtin, FuncName: fn.Name})
+		if err := e.checkArgs(node, fn, args); err != nil {
 			return nil, err
 		}
-		result, err := fn.Fn(env, args)
+		if err = e.enterCall(newStackFrame(node, fn.Name)); err != nil {
+			return nil, err
+		}
+		result, err := fn.Fn(e.ctx, env, args)
 		if err != nil {
+			// builtin stopped because of cancellation, it is not a failure of the builtin itself
+			if ctxErr := e.checkContext(node); ctxErr != nil {
+				err = ctxErr
+			} else {
+				err = positionBuiltinError(node, err)
+			}
+		}
+		if err = e.leaveCall(err); err != nil {
 			return nil, err
 		}
 
@@ -354,9 +552,30 @@ func (e *ExecAstVisitor) execFunctionCall(node *ast.FunctionCall, env *object.En
 		return result, nil
 
 	default:
-		return nil, runtimeError(node, "not a function: %s", fn.Type())
+		return nil, runtimeError(node, diag.NotAFunction, fn.Type())
+	}
+}
+func (e *ExecAstVisitor) execTypeConversion(
+	node *ast.FunctionCall,
+	targetType string,
+	env *object.Environment,
+) (object.Object, error) {
+	e.operation(Operation{Type: TypeConversion})
+	if len(node.Arguments) != 1 {
+		return nil, runtimeError(node, diag.ConversionArgumentsCount, targetType, len(node.Arguments))
+	}
+	value, err := e.execExpression(node.Arguments[0], env)
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := convertValue(value, targetType, env)
+	if !ok {
+		return nil, runtimeError(node, diag.ConversionFailed, value.Type(), targetType)
 	}
+	return result, nil
 }
+
 func (e *ExecAstVisitor) execExpressionList(expressions []ast.IExpression, env *object.Environment) ([]object.Object, error) {
 	var result []object.Object
 
@@ -372,42 +591,131 @@ func (e *ExecAstVisitor) execExpressionList(expressions []ast.IExpression, env *
 }
 
 func (e *ExecAstVisitor) execIfStatement(node *ast.IfStatement, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: IfStmt})
+	e.operation(Operation{Type: IfStmt})
 	condition, err := e.execExpression(node.Condition, env)
 	if err != nil {
 		return nil, err
 	}
-	if condition.Type() != object.TypeBool {
-		return nil, runtimeError(node, "Condition should be boolean type but %s in fact", condition.Type())
+	conditionResult, ok := condition.(*object.Boolean)
+	if !ok {
+		return nil, runtimeError(node, diag.ConditionNotBool, condition.Type())
+	}
+	if conditionResult.Empty {
+		return nil, runtimeError(node, diag.EmptyCondition)
 	}
 
-	if condition == ReservedObjTrue {
-		return e.execStatementsBlock(node.PositiveBranch, env)
+	if conditionResult.Value {
+		return e.execStatementsBlock(node.PositiveBranch, object.NewEnclosedEnvironment(env))
 	} else if node.ElseBranch != nil {
-		return e.execStatementsBlock(node.ElseBranch, env)
+		return e.execStatementsBlock(node.ElseBranch, object.NewEnclosedEnvironment(env))
 	} else {
 		return nil, nil
 	}
 }
 
+func (e *ExecAstVisitor) execTryStatement(node *ast.TryStatement, env *object.Environment) (object.Object, error) {
+	e.operation(Operation{Type: TryStmt})
+	result, err := e.execStatementsBlock(node.Body, object.NewEnclosedEnvironment(env))
+	if err == nil {
+		return result, nil
+	}
+	if !IsRecoverable(err) {
+		return nil, err
+	}
+
+	recoverEnv := object.NewEnclosedEnvironment(env)
+	if node.ErrVar != nil {
+		// stack trace is for the host, the program gets only the message
+		var traced *StackTraceError
+		if errors.As(err, &traced) {
+			err = traced.Err
+		}
+		diag.Localize(err, e.locale)
+		recoverEnv.Set(node.ErrVar.Value, &object.Error{Message: err.Error()})
+	}
+	return e.execStatementsBlock(node.RecoverBranch, recoverEnv)
+}
+
+func (e *ExecAstVisitor) execAssert(node *ast.Assert, env *object.Environment) (object.Object, error) {
+	if e.assertMode == AssertStrip {
+		return nil, nil
+	}
+	e.operation(Operation{Type: AssertStmt})
+	condition, err := e.execExpression(node.Condition, env)
+	if err != nil {
+		return nil, err
+	}
+	conditionResult, ok := condition.(*object.Boolean)
+	if !ok {
+		return nil, runtimeError(node, diag.AssertConditionNotBool, condition.Type())
+	}
+	if conditionResult.Value && !conditionResult.Empty {
+		return nil, nil
+	}
+
+	assertionErr := newAssertionError(node.ConditionText, node.Message, node.Token.Line, node.Token.Col)
+	if e.assertMode == AssertLog {
+		diag.Localize(assertionErr, e.locale)
+		e.assertLogger(assertionErr)
+		return nil, nil
+	}
+	return nil, assertionErr
+}
+
+func (e *ExecAstVisitor) execIfExpression(node *ast.IfExpression, env *object.Environment) (object.Object, error) {
+	e.operation(Operation{Type: IfExpr})
+	positiveType, positiveTypeKnown := e.inferExpressionType(node.PositiveBranch, env)
+	elseType, elseTypeKnown := e.inferExpressionType(node.ElseBranch, env)
+	if positiveTypeKnown && elseTypeKnown && positiveType != elseType {
+		return nil, runtimeError(node, diag.IfBranchesTypeMismatch, positiveType, elseType)
+	}
+
+	condition, err := e.execExpression(node.Condition, env)
+	if err != nil {
+		return nil, err
+	}
+	conditionResult, ok := condition.(*object.Boolean)
+	if !ok {
+		return nil, runtimeError(node, diag.ConditionNotBool, condition.Type())
+	}
+	if conditionResult.Empty {
+		return nil, runtimeError(node, diag.EmptyCondition)
+	}
+
+	branch, otherType, otherTypeKnown := node.PositiveBranch, elseType, elseTypeKnown
+	if !conditionResult.Value {
+		branch, otherType, otherTypeKnown = node.ElseBranch, positiveType, positiveTypeKnown
+	}
+	result, err := e.execExpression(branch, env)
+	if err != nil {
+		return nil, err
+	}
+	if otherTypeKnown && string(result.Type()) != otherType {
+		return nil, runtimeError(node, diag.IfBranchesTypeMismatch, result.Type(), otherType)
+	}
+
+	return result, nil
+}
+
 func (e *ExecAstVisitor) execArray(node *ast.Array, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: Array})
+	e.operation(Operation{Type: Array})
 	elements, err := e.execExpressionList(node.Elements, env)
 	if err != nil {
 		return nil, err
 	}
-	if err = arrayElementsTypeCheck(node, node.ElementsType, elements); err != nil {
+	elementsType := env.ResolveType(node.ElementsType)
+	if err = arrayElementsTypeCheck(node, elementsType, elements); err != nil {
 		return nil, err
 	}
 
 	return &object.Array{
-		ElementsType: node.ElementsType,
+		ElementsType: elementsType,
 		Elements:     elements,
 	}, nil
 }
 
 func (e *ExecAstVisitor) execArrayIndexCall(node *ast.ArrayIndexCall, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: ArrayIndex})
+	e.operation(Operation{Type: ArrayIndex})
 	left, err := e.execExpression(node.Left, env)
 	if err != nil {
 		return nil, err
@@ -420,27 +728,27 @@ func (e *ExecAstVisitor) execArrayIndexCall(node *ast.ArrayIndexCall, env *objec
 
 	arrayObj, ok := left.(*object.Array)
 	if !ok {
-		return nil, runtimeError(node, "Array access can be only on arrays but '%s' given", left.Type())
+		return nil, runtimeError(node, diag.IndexOnNonArray, left.Type())
 	}
 
 	indexObj, ok := index.(*object.Integer)
 	if !ok {
-		return nil, runtimeError(node, "Array access can be only by 'int' type but '%s' given", index.Type())
+		return nil, runtimeError(node, diag.IndexNotInt, index.Type())
 	}
 
 	i := indexObj.Value
 	if i < 0 || int(i) > len(arrayObj.Elements)-1 {
-		return nil, runtimeError(node, "Array access out of bounds: '%d'", i)
+		return nil, runtimeError(node, diag.IndexOutOfBounds, i)
 	}
 
 	return arrayObj.Elements[i], nil
 }
 
 func (e *ExecAstVisitor) execStruct(node *ast.Struct, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: Struct})
-	definition, ok := env.GetStructDefinition(node.Ident.Value)
+	e.operation(Operation{Type: Struct})
+	definition, ok := env.GetStructDefinition(env.ResolveType(node.Ident.Value))
 	if !ok {
-		return nil, runtimeError(node, "Struct '%s' is not defined", node.Ident.Value)
+		return nil, runtimeError(node, diag.UndefinedStruct, node.Ident.Value)
 	}
 	fields := make(map[string]object.Object)
 	for _, n := range node.Fields {
@@ -452,12 +760,14 @@ func (e *ExecAstVisitor) execStruct(node *ast.Struct, env *object.Environment) (
 		if err = structTypeAndVarsChecks(n, definition, result); err != nil {
 			return nil, err
 		}
+		if err = e.checkFinite(n.Value, n.Left.Value, result); err != nil {
+			return nil, err
+		}
 
 		fields[n.Left.Value] = result
 	}
 	if len(fields) != len(definition.Fields) {
-		return nil, runtimeError(node,
-			"Var of struct '%s' should have %d fields filled but in fact only %d",
+		return nil, runtimeError(node, diag.StructFieldsNotFilled,
 			definition.Name,
 			len(definition.Fields),
 			len(fields))
@@ -471,7 +781,7 @@ func (e *ExecAstVisitor) execStruct(node *ast.Struct, env *object.Environment) (
 }
 
 func (e *ExecAstVisitor) execStructFieldCall(node *ast.StructFieldCall, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: StructFieldCall})
+	e.operation(Operation{Type: StructFieldCall})
 	left, err := e.execExpression(node.StructExpr, env)
 	if err != nil {
 		return nil, err
@@ -479,20 +789,36 @@ func (e *ExecAstVisitor) execStructFieldCall(node *ast.StructFieldCall, env *obj
 
 	structObj, ok := left.(*object.Struct)
 	if !ok {
-		return nil, runtimeError(node, "Field access can be only on struct but '%s' given", left.Type())
+		return nil, runtimeError(node, diag.FieldAccessOnNonStruct, left.Type())
+	}
+
+	if structObj.Empty {
+		fieldType, ok := structObj.Definition.Fields[node.Field.Value]
+		if !ok {
+			return nil, unknownNameError(node, diag.UnknownField, node.Field.Value, structObj.Definition.FieldNames(),
+				structObj.Definition.Name, node.Field.Value)
+		}
+		if !node.IsSafe {
+			return nil, runtimeError(node, diag.EmptyStructFieldRead, node.Field.Value, structObj.Definition.Name)
+		}
+		emptyValue, ok := createEmptyValue(fieldType, env)
+		if !ok {
+			return nil, runtimeError(node, diag.FieldCantBeEmpty, node.Field.Value, fieldType)
+		}
+		return emptyValue, nil
 	}
 
 	fieldObj, ok := structObj.Fields[node.Field.Value]
 	if !ok {
-		return nil, runtimeError(node,
-			"Struct '%s' doesn't have field '%s'", structObj.Definition.Name, node.Field.Value)
+		return nil, unknownNameError(node, diag.UnknownField, node.Field.Value, structObj.Definition.FieldNames(),
+			structObj.Definition.Name, node.Field.Value)
 	}
 
 	return fieldObj, nil
 }
 
 func (e *ExecAstVisitor) execEnumElementCall(node *ast.EnumElementCall, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: EnumElementCall})
+	e.operation(Operation{Type: EnumElementCall})
 	left, err := e.execExpression(node.EnumExpr, env)
 	if err != nil {
 		return nil, err
@@ -500,7 +826,7 @@ func (e *ExecAstVisitor) execEnumElementCall(node *ast.EnumElementCall, env *obj
 
 	enumObj, ok := left.(*object.Enum)
 	if !ok {
-		return nil, runtimeError(node, "Expected enum, got '%s'", left.Type())
+		return nil, runtimeError(node, diag.EnumExpected, left.Type())
 	}
 
 	found := false
@@ -512,27 +838,29 @@ func (e *ExecAstVisitor) execEnumElementCall(node *ast.EnumElementCall, env *obj
 		}
 	}
 	if !found {
-		return nil, runtimeError(node,
-			"Enum '%s' doesn't have element '%s'", enumObj.Definition.Name, node.Element.Value)
+		return nil, unknownNameError(node, diag.UnknownEnumElement, node.Element.Value, enumObj.Definition.Elements,
+			enumObj.Definition.Name, node.Element.Value)
 	}
 
 	return enumObj, nil
 }
 
 func (e *ExecAstVisitor) execSwitch(node *ast.Switch, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: Switch})
+	e.operation(Operation{Type: Switch})
 	for _, c := range node.Cases {
 		condition, err := e.execExpression(c.Condition, env)
 		if err != nil {
 			return nil, err
 		}
 		if condition.Type() != object.TypeBool {
-			return nil, runtimeError(c.Condition,
-				"Result of case condition should be 'boolean' but '%s' given", condition.Type())
+			return nil, runtimeError(c.Condition, diag.CaseConditionNotBool, condition.Type())
 		}
 		conditionResult, _ := condition.(*object.Boolean)
+		if conditionResult.Empty {
+			return nil, runtimeError(c.Condition, diag.EmptyCaseCondition)
+		}
 		if conditionResult.Value {
-			result, err := e.execStatementsBlock(c.PositiveBranch, env)
+			result, err := e.execStatementsBlock(c.PositiveBranch, object.NewEnclosedEnvironment(env))
 			if err != nil {
 				return nil, err
 			}
@@ -543,7 +871,7 @@ func (e *ExecAstVisitor) execSwitch(node *ast.Switch, env *object.Environment) (
 		}
 	}
 	if node.DefaultBranch != nil {
-		result, err := e.execStatementsBlock(node.DefaultBranch, env)
+		result, err := e.execStatementsBlock(node.DefaultBranch, object.NewEnclosedEnvironment(env))
 		if err != nil {
 			return nil, err
 		}
@@ -555,16 +883,16 @@ func (e *ExecAstVisitor) execSwitch(node *ast.Switch, env *object.Environment) (
 }
 
 func (e *ExecAstVisitor) execNumInt(node *ast.NumInt, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: NumInt})
+	e.operation(Operation{Type: NumInt})
 	return &object.Integer{Value: node.Value}, nil
 }
 
 func (e *ExecAstVisitor) execNumFloat(node *ast.NumFloat, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: NumFloat})
+	e.operation(Operation{Type: NumFloat})
 	return &object.Float{Value: node.Value}, nil
 }
 
 func (e *ExecAstVisitor) execBoolean(node *ast.Boolean, env *object.Environment) (object.Object, error) {
-	e.execCallback(Operation{Type: Boolean})
+	e.operation(Operation{Type: Boolean})
 	return nativeBooleanToBoolean(node.Value), nil
 }
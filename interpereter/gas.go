@@ -0,0 +1,72 @@
+package interpereter
+
+import (
+	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
+)
+
+// GasCosts is gas consumed by operations. Operations missing in Operations cost Default
+type GasCosts struct {
+	Default    int64
+	Operations map[OperationType]int64
+	// cost of builtin function by name, it is consumed in addition to the cost of Builtin operation
+	Builtins map[string]int64
+}
+
+// DefaultGasCosts makes every operation cost 1 gas
+func DefaultGasCosts() GasCosts {
+	return GasCosts{Default: 1}
+}
+
+func (c GasCosts) cost(op Operation) int64 {
+	cost, ok := c.Operations[op.Type]
+	if !ok {
+		cost = c.Default
+	}
+	if op.Type == Builtin {
+		cost += c.Builtins[op.FuncName]
+	}
+	return cost
+}
+
+// OutOfGasError aborts execution when operations consumed more gas than the limit set by SetGasLimit.
+// GasUsed includes the operation that exceeded the limit
+type OutOfGasError struct {
+	GasLimit int64
+	GasUsed  int64
+	fatalError
+}
+
+// SetGasLimit limits gas consumed by one ExecAst call. 0 or less means no limit
+func (e *ExecAstVisitor) SetGasLimit(limit int64) {
+	e.gasLimit = limit
+}
+
+func (e *ExecAstVisitor) SetGasCosts(costs GasCosts) {
+	e.gasCosts = costs
+}
+
+// GasUsed returns gas consumed by the last ExecAst call
+func (e *ExecAstVisitor) GasUsed() int64 {
+	return e.gasUsed
+}
+
+// operation reports operation to the exec callback and consumes its gas
+func (e *ExecAstVisitor) operation(op Operation) {
+	e.execCallback(op)
+	e.gasUsed += e.gasCosts.cost(op)
+}
+
+// checkGas aborts execution if the gas limit is exceeded. It is checked after every statement
+// and before every function call, so endless recursion is stopped too
+func (e *ExecAstVisitor) checkGas(node ast.INode) error {
+	if e.gasLimit <= 0 || e.gasUsed <= e.gasLimit {
+		return nil
+	}
+	t := node.GetToken()
+	return &OutOfGasError{
+		GasLimit:   e.gasLimit,
+		GasUsed:    e.gasUsed,
+		fatalError: newFatalError(t.Line, t.Col, diag.OutOfGas, e.gasUsed, e.gasLimit),
+	}
+}
@@ -2,10 +2,12 @@ package interpereter
 
 import (
 	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/object"
+	"github.com/justclimber/marslang/token"
 
 	"errors"
-	"fmt"
+	"strings"
 )
 
 var (
@@ -14,37 +16,155 @@ var (
 )
 
 func registerStructDefinition(node *ast.StructDefinition, env *object.Environment) error {
-	s := &object.StructDefinition{
-		Name:   node.Name,
-		Fields: object.CreateVarDefinitionsFromVarType(node.Fields),
+	s := object.NewStructDefinition(node.Name, node.Fields)
+	for name, fieldType := range s.Fields {
+		s.Fields[name] = env.ResolveType(fieldType)
 	}
 	if err := env.RegisterStructDefinition(s); err != nil {
-		return err
+		return runtimeError(node, diag.StructRedefinition, node.Name)
 	}
 	return nil
 }
 
+func registerTypeDefinition(node *ast.TypeDefinition, env *object.Environment) error {
+	if isBasicType(node.Name) || isUserDefinedType(node.Name, env) {
+		return runtimeError(node, diag.TypeRedefinition, node.Name)
+	}
+	underlying := env.ResolveType(node.Underlying)
+	if !isKnownType(underlying, env) {
+		return runtimeError(node, diag.UnknownType, node.Underlying)
+	}
+	if base, ok := env.GetTypeDefinition(underlying); ok && !node.IsAlias {
+		underlying = base.Underlying
+	}
+	if !node.IsAlias && underlying != object.TypeInt && underlying != object.TypeFloat {
+		return runtimeError(node, diag.InvalidDistinctType, underlying)
+	}
+
+	td := &object.TypeDefinition{
+		Name:       node.Name,
+		Underlying: underlying,
+		IsAlias:    node.IsAlias,
+	}
+	if err := env.RegisterTypeDefinition(td); err != nil {
+		return runtimeError(node, diag.TypeRedefinition, node.Name)
+	}
+	return nil
+}
+
+func isBasicType(t string) bool {
+	return t == object.TypeInt || t == object.TypeFloat || t == object.TypeBool || t == object.TypeVoid
+}
+
+func isUserDefinedType(t string, env *object.Environment) bool {
+	if _, ok := env.GetStructDefinition(t); ok {
+		return true
+	}
+	if _, ok := env.GetEnumDefinition(t); ok {
+		return true
+	}
+	_, ok := env.GetTypeDefinition(t)
+	return ok
+}
+
+func isKnownType(t string, env *object.Environment) bool {
+	t = strings.TrimPrefix(t, "[]")
+	return (isBasicType(t) && t != object.TypeVoid) || isUserDefinedType(t, env)
+}
+
+// isConversionType checks whether the name can be used for type conversion like `float(a)` or `Angle(a)`
+func isConversionType(name string, env *object.Environment) bool {
+	name = env.ResolveType(name)
+	if name == object.TypeInt || name == object.TypeFloat {
+		return true
+	}
+	_, ok := env.GetTypeDefinition(name)
+	return ok
+}
+
+// convertValue converts numeric value to int, float or distinct type based on them
+func convertValue(value object.Object, targetType string, env *object.Environment) (object.Object, bool) {
+	targetType = env.ResolveType(targetType)
+	var named object.Named
+	if td, ok := env.GetTypeDefinition(targetType); ok {
+		named.TypeDefinition = td
+		targetType = td.Underlying
+	}
+
+	switch targetType {
+	case object.TypeInt:
+		switch v := value.(type) {
+		case *object.Integer:
+			return &object.Integer{Emptier: v.Emptier, Named: named, Value: v.Value}, true
+		case *object.Float:
+			return &object.Integer{Emptier: v.Emptier, Named: named, Value: int64(v.Value)}, true
+		}
+	case object.TypeFloat:
+		switch v := value.(type) {
+		case *object.Integer:
+			return &object.Float{Emptier: v.Emptier, Named: named, Value: float64(v.Value)}, true
+		case *object.Float:
+			return &object.Float{Emptier: v.Emptier, Named: named, Value: v.Value}, true
+		}
+	}
+	return nil, false
+}
+
 func registerEnumDefinition(node *ast.EnumDefinition, env *object.Environment) error {
 	ed := &object.EnumDefinition{
 		Name:     node.Name,
 		Elements: node.Elements,
 	}
 	if err := env.RegisterEnumDefinition(ed); err != nil {
-		return err
+		return runtimeError(node, diag.EnumRedefinition, node.Name)
 	}
 	return nil
 }
 
+// createEmptyValue creates typed empty value ("?type" in the language). Returns false if type can't be empty
+func createEmptyValue(varType string, env *object.Environment) (object.Object, bool) {
+	empty := object.Emptier{Empty: true}
+	varType = env.ResolveType(varType)
+	if strings.HasPrefix(varType, "[]") {
+		elementsType := strings.TrimPrefix(varType, "[]")
+		if _, ok := createEmptyValue(elementsType, env); !ok {
+			return nil, false
+		}
+		return &object.Array{Emptier: empty, ElementsType: elementsType}, true
+	}
+
+	switch varType {
+	case object.TypeInt:
+		return &object.Integer{Emptier: empty}, true
+	case object.TypeFloat:
+		return &object.Float{Emptier: empty}, true
+	case object.TypeBool:
+		return &object.Boolean{Emptier: empty}, true
+	}
+	if def, ok := env.GetStructDefinition(varType); ok {
+		return object.NewEmptyStruct(def), true
+	}
+	if ed, ok := env.GetEnumDefinition(varType); ok {
+		return &object.Enum{Emptier: empty, Definition: ed}, true
+	}
+	if td, ok := env.GetTypeDefinition(varType); ok {
+		value, ok := createEmptyValue(td.Underlying, env)
+		if !ok {
+			return nil, false
+		}
+		return convertValue(value, td.Name, env)
+	}
+	return nil, false
+}
+
 func structTypeAndVarsChecks(n *ast.Assignment, definition *object.StructDefinition, result object.Object) error {
 	fieldType, ok := definition.Fields[n.Left.Value]
 	if !ok {
-		return runtimeError(
-			n, "Struct '%s' doesn't have the field '%s' in the definition", definition.Name, n.Left.Value)
+		return unknownNameError(n, diag.UnknownField, n.Left.Value, definition.FieldNames(),
+			definition.Name, n.Left.Value)
 	}
 	if fieldType != string(result.Type()) {
-		return runtimeError(
-			n,
-			"Field '%s' defined as '%s' but '%s' given",
+		return runtimeError(n, diag.FieldTypeMismatch,
 			n.Left.Value,
 			fieldType,
 			result.Type())
@@ -55,32 +175,38 @@ func structTypeAndVarsChecks(n *ast.Assignment, definition *object.StructDefinit
 func arrayElementsTypeCheck(node *ast.Array, t string, es []object.Object) error {
 	for i, el := range es {
 		if string(el.Type()) != t {
-			return runtimeError(node, "Array element #%d should be type '%s' but '%s' given", i+1, t, el.Type())
+			return runtimeError(node, diag.ArrayElementTypeMismatch, i+1, t, el.Type())
 		}
 	}
 	return nil
 }
 
 func functionReturnTypeCheck(node *ast.FunctionCall, result object.Object, functionReturnType string) error {
+	// host builtin may return no value without error
+	if result == nil {
+		return runtimeError(node, diag.ReturnTypeMismatch, functionReturnType, "nil")
+	}
 	if result.Type() != object.ObjectType(functionReturnType) {
-		return runtimeError(node,
-			"Return type mismatch: function declared as '%s' but in fact return '%s'",
-			functionReturnType, result.Type())
+		return runtimeError(node, diag.ReturnTypeMismatch, functionReturnType, result.Type())
 	}
 	return nil
 }
 
-func functionCallArgumentsCheck(node *ast.FunctionCall, declaredArgs []*ast.VarAndType, actualArgValues []object.Object) error {
+func functionCallArgumentsCheck(
+	node *ast.FunctionCall,
+	declaredArgs []*ast.VarAndType,
+	actualArgValues []object.Object,
+	env *object.Environment,
+) error {
 	if len(declaredArgs) != len(actualArgValues) {
-		return runtimeError(node, "Function call arguments count mismatch: declared %d, but called %d",
-			len(declaredArgs), len(actualArgValues))
+		return runtimeError(node, diag.ArgumentsCountMismatch, len(declaredArgs), len(actualArgValues))
 	}
 
 	if len(actualArgValues) > 0 {
 		for i, arg := range declaredArgs {
-			if actualArgValues[i].Type() != object.ObjectType(arg.VarType) {
-				return runtimeError(arg, "argument #%d type mismatch: expected '%s' by func declaration but called '%s'",
-					i+1, arg.VarType, actualArgValues[i].Type())
+			argType := env.ResolveType(arg.VarType)
+			if actualArgValues[i].Type() != object.ObjectType(argType) {
+				return runtimeError(arg, diag.ArgumentTypeMismatch, i+1, argType, actualArgValues[i].Type())
 			}
 		}
 	}
@@ -98,8 +224,71 @@ func transferArgsToNewEnv(fn *object.Function, args []object.Object) *object.Env
 	return env
 }
 
-func runtimeError(node ast.INode, format string, args ...interface{}) error {
-	msg := fmt.Sprintf(format, args...)
-	t := node.GetToken()
-	return errors.New(fmt.Sprintf("%s\nline:%d, pos %d", msg, t.Line, t.Col))
+func runtimeError(node ast.INode, code diag.Code, args ...interface{}) error {
+	return diag.New(diag.PhaseRuntime, code, node.GetToken(), args...)
+}
+
+// unknownNameError is runtimeError for unknown name with "did you mean" hint made from close candidates
+func unknownNameError(node ast.INode, code diag.Code, name string, candidates []string, args ...interface{}) error {
+	return diag.New(diag.PhaseRuntime, code, node.GetToken(), args...).
+		WithSuggestions(diag.Suggest(name, candidates))
+}
+
+// FatalError aborts program execution and can't be caught by try/recover block.
+// Host builtins should wrap with it failures that the program must not handle itself.
+// All other errors returned by builtins (e.g. created with BuiltinFuncError) are recoverable
+type FatalError struct {
+	Err error
+}
+
+func (e *FatalError) Error() string { return e.Err.Error() }
+func (e *FatalError) Unwrap() error { return e.Err }
+func (e *FatalError) Fatal() bool   { return true }
+
+// fatalError is embedded by errors of the interpreter that abort execution. Such error is diag.Error
+// for errors.As, so it is rendered and localized like all others
+type fatalError struct {
+	Line    int
+	Col     int
+	diagErr *diag.Error
+}
+
+func newFatalError(line, col int, code diag.Code, args ...interface{}) fatalError {
+	t := token.Token{Line: line, Col: col}
+	return fatalError{Line: line, Col: col, diagErr: diag.New(diag.PhaseRuntime, code, t, args...)}
+}
+
+func (e *fatalError) Error() string { return e.diagErr.Error() }
+func (e *fatalError) Unwrap() error { return e.diagErr }
+func (e *fatalError) Fatal() bool   { return true }
+
+// IsFatal checks whether error can't be recovered by try/recover block
+func IsFatal(err error) bool {
+	var f interface{ Fatal() bool }
+	return errors.As(err, &f) && f.Fatal()
+}
+
+// recoverableCodes are runtime failures caused by values the program works with. All other errors
+// (type mismatches, unknown names and so on) are bugs of the program itself, try/recover block doesn't hide them
+var recoverableCodes = map[diag.Code]bool{
+	diag.DivisionByZero:             true,
+	diag.IntOverflow:                true,
+	diag.NotFiniteValue:             true,
+	diag.EmptyCondition:             true,
+	diag.EmptyCaseCondition:         true,
+	diag.EmptyBoolOperand:           true,
+	diag.IndexOutOfBounds:           true,
+	diag.EmptyStructFieldRead:       true,
+	diag.EmptyStructFieldAssignment: true,
+	diag.BuiltinFailed:              true,
+}
+
+// IsRecoverable checks whether error can be caught by try/recover block:
+// runtime failures and errors of builtin functions that are not fatal
+func IsRecoverable(err error) bool {
+	if IsFatal(err) {
+		return false
+	}
+	var diagErr *diag.Error
+	return errors.As(err, &diagErr) && recoverableCodes[diagErr.Code]
 }
@@ -1,6 +1,7 @@
 package interpereter
 
 import (
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/lexer"
 	"github.com/justclimber/marslang/object"
 	"github.com/justclimber/marslang/parser"
@@ -8,8 +9,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"context"
+	"errors"
 	"log"
+	"math"
 	"testing"
+	"time"
 )
 
 func TestParenthesis(t *testing.T) {
@@ -84,6 +89,52 @@ b = f(Colors:blue)
 	require.Equal(t, false, varBBool.Value)
 }
 
+func TestEmptyEnumInspect(t *testing.T) {
+	input := `enum Nothing {}
+enum Colors {red, green, blue}
+a = ?Nothing
+b = ?Colors
+c = Colors:red
+`
+	env := testExecAngGetEnv(t, input)
+
+	assert.Equal(t, []string{"a: ?Nothing\n", "b: ?Colors\n", "c: red\n"}, env.ToStrings())
+}
+
+func TestEmptyBool(t *testing.T) {
+	input := `a = ?bool
+b = a == ?bool
+c = a != true
+d = true == a
+`
+	env := testExecAngGetEnv(t, input)
+
+	assert.Equal(t, []string{"a: ?bool\n", "b: true\n", "c: true\n", "d: false\n"}, env.ToStrings())
+}
+
+func TestEmptyBoolNegative(t *testing.T) {
+	for _, input := range []string{
+		"a = !?bool\n",
+		"a = ?bool && true\n",
+		"a = false || ?bool\n",
+	} {
+		l := lexer.New(input)
+		p, err := parser.New(l)
+		require.Nil(t, err, input)
+		astProgram, err := p.Parse()
+		require.Nil(t, err, input)
+
+		env := object.NewEnvironment()
+		err = NewExecAstVisitor().ExecAst(astProgram, env)
+		var diagErr *diag.Error
+		require.True(t, errors.As(err, &diagErr), input)
+		assert.Equal(t, diag.EmptyBoolOperand, diagErr.Code, input)
+		assert.True(t, IsRecoverable(err), input)
+		_, ok := env.Get("a")
+		assert.False(t, ok, input)
+	}
+}
+
 func TestEnumArray(t *testing.T) {
 	input := `enum Colors {red, green, blue}
 f = fn([]Colors c) bool {
@@ -297,6 +348,7 @@ pts = ?[]point
 
 func TestExecEmptyBuiltin(t *testing.T) {
 	input := `a = ?int
+b = 0
 if empty(a) {
 b = 5
 }
@@ -309,8 +361,69 @@ b = 5
 	require.IsType(t, &object.Integer{}, varB)
 }
 
+func TestNullCoalesceAndSafeFieldCall(t *testing.T) {
+	input := `struct point {
+   float x
+   []int ids
+}
+enum Colors {red, green, blue}
+a = ?int
+b = a ?? 5
+c = 3 ?? 5
+p = ?point
+px = p?.x ?? 1.5
+ids = p?.ids
+e = empty(ids)
+eb = empty(?bool)
+col = ?Colors
+ec = empty(col)
+col2 = col ?? Colors:blue
+isRed = col == Colors:red
+`
+	env := testExecAngGetEnv(t, input)
+
+	for name, expected := range map[string]object.Object{
+		"b":     &object.Integer{Value: 5},
+		"c":     &object.Integer{Value: 3},
+		"px":    &object.Float{Value: 1.5},
+		"e":     ReservedObjTrue,
+		"eb":    ReservedObjTrue,
+		"ec":    ReservedObjTrue,
+		"isRed": ReservedObjFalse,
+	} {
+		v, ok := env.Get(name)
+		require.True(t, ok, name)
+		assert.Equal(t, expected.Inspect(), v.Inspect(), name)
+	}
+
+	varCol2, ok := env.Get("col2")
+	require.True(t, ok)
+	require.IsType(t, &object.Enum{}, varCol2)
+	assert.Equal(t, int8(2), varCol2.(*object.Enum).Value)
+	assert.False(t, varCol2.(*object.Enum).Empty)
+}
+
+func TestEmptierNegative(t *testing.T) {
+	for _, input := range []string{
+		"struct point {\nfloat x\n}\np = ?point\nx = p.x\n",
+		"struct point {\nfloat x\n}\np = ?point\np.x = 1.\n",
+		"a = ?int\nb = a ?? 1.\n",
+		"f = fn() int {\nreturn 1\n}\ng = f ?? f\n",
+		"a = ?bool\nif a {\nb = 1\n}\n",
+	} {
+		l := lexer.New(input)
+		p, err := parser.New(l)
+		require.Nil(t, err, input)
+		astProgram, err := p.Parse()
+		require.Nil(t, err, input)
+		err = NewExecAstVisitor().ExecAst(astProgram, object.NewEnvironment())
+		require.NotNil(t, err, input)
+	}
+}
+
 func TestExecIfAndSimpleBoolean(t *testing.T) {
 	input := `a = true
+b = 0
 if a {
 b = 5
 }
@@ -335,7 +448,9 @@ func TestExecIfStatement(t *testing.T) {
 }
 
 func TestExecIfStatementWithElseBranch(t *testing.T) {
-	input := `if 4 > 3 {
+	input := `a = 0
+b = 0
+if 4 > 3 {
     a = 10
 } else {
     b = 20
@@ -351,8 +466,338 @@ func TestExecIfStatementWithElseBranch(t *testing.T) {
 	varAInt, ok := varA.(*object.Integer)
 	require.Equal(t, int64(10), varAInt.Value)
 
-	_, ok = env.Get("b")
-	require.False(t, ok)
+	varB, ok := env.Get("b")
+	require.True(t, ok)
+	require.Equal(t, int64(0), varB.(*object.Integer).Value)
+}
+
+func TestBlockScope(t *testing.T) {
+	input := `a = 1
+if a > 0 {
+   tmp = 2
+   a = tmp
+}
+switch {
+case a > 1
+   tmp = 3.
+   a = 3
+default
+   tmp = true
+}
+tmp = 10
+`
+	env := testExecAngGetEnv(t, input)
+
+	varA, ok := env.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, int64(3), varA.(*object.Integer).Value)
+
+	varTmp, ok := env.Get("tmp")
+	require.True(t, ok)
+	require.IsType(t, &object.Integer{}, varTmp)
+	assert.Equal(t, int64(10), varTmp.(*object.Integer).Value)
+}
+
+func TestIfExpression(t *testing.T) {
+	input := `angleTo = -0.5
+rotate = if angleTo > 0. { 1. } else { -1. }
+f = fn(int x) int {
+   return x * 2
+}
+a = 7
+size = if a < 5 { f(1) } else if a < 10 { f(2) } else { 0 }
+`
+	env := testExecAngGetEnv(t, input)
+
+	varRotate, ok := env.Get("rotate")
+	require.True(t, ok)
+	require.IsType(t, &object.Float{}, varRotate)
+	assert.Equal(t, -1., varRotate.(*object.Float).Value)
+
+	varSize, ok := env.Get("size")
+	require.True(t, ok)
+	require.IsType(t, &object.Integer{}, varSize)
+	assert.Equal(t, int64(4), varSize.(*object.Integer).Value)
+}
+
+func TestIfExpressionBranchesTypeMismatchNegative(t *testing.T) {
+	for _, input := range []string{
+		"a = if true { 1 } else { 1. }\n",
+		"b = 2.\na = if false { 1 } else { b }\n",
+		"a = if 1 { 1 } else { 2 }\n",
+	} {
+		l := lexer.New(input)
+		p, err := parser.New(l)
+		require.Nil(t, err, input)
+		astProgram, err := p.Parse()
+		require.Nil(t, err, input)
+		err = NewExecAstVisitor().ExecAst(astProgram, object.NewEnvironment())
+		require.NotNil(t, err, input)
+	}
+}
+
+func TestTryRecover(t *testing.T) {
+	input := `arr = []int{1, 2}
+a = 0
+recovered = false
+try {
+   a = arr[5]
+   a = 100
+} recover err {
+   recovered = true
+   e = err
+}
+safeGet = fn([]int arr, int i) int {
+   try {
+      return arr[i]
+   } recover {
+      return -1
+   }
+   return 0
+}
+b = safeGet(arr, 1)
+c = safeGet(arr, 10)
+`
+	env := testExecAngGetEnv(t, input)
+
+	varA, _ := env.Get("a")
+	assert.Equal(t, int64(0), varA.(*object.Integer).Value)
+	varRecovered, _ := env.Get("recovered")
+	assert.Equal(t, ReservedObjTrue, varRecovered)
+	_, ok := env.Get("e")
+	assert.False(t, ok)
+	varB, _ := env.Get("b")
+	assert.Equal(t, int64(2), varB.(*object.Integer).Value)
+	varC, _ := env.Get("c")
+	assert.Equal(t, int64(-1), varC.(*object.Integer).Value)
+}
+
+func TestTryDoesNotRecoverProgramBugs(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		code  diag.Code
+	}{
+		"type mismatch": {"try {\n   a = 1 + 2.\n} recover {\n   a = 0\n}\n", diag.OperandTypesMismatch},
+		"unknown name":  {"try {\n   a = xelon\n} recover {\n   a = 0\n}\n", diag.IdentifierNotFound},
+		"arguments":     {"f = fn(int a) int {\n   return a\n}\ntry {\n   a = f(1.)\n} recover {\n   a = 0\n}\n", diag.ArgumentTypeMismatch},
+	}
+	for name, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := parser.New(l)
+		require.Nil(t, err, name)
+		astProgram, err := p.Parse()
+		require.Nil(t, err, name)
+
+		env := object.NewEnvironment()
+		err = NewExecAstVisitor().ExecAst(astProgram, env)
+		require.NotNil(t, err, name)
+		var diagErr *diag.Error
+		require.True(t, errors.As(err, &diagErr), name)
+		assert.Equal(t, tt.code, diagErr.Code, name)
+		assert.False(t, IsRecoverable(err), name)
+		_, ok := env.Get("a")
+		assert.False(t, ok, name)
+	}
+}
+
+func TestTryRecoverBuiltinErrors(t *testing.T) {
+	input := `r = 0
+try {
+   soft()
+} recover err {
+   r = 1
+}
+try {
+   hard()
+} recover {
+   r = 2
+}
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	env := object.NewEnvironment()
+	e := NewExecAstVisitor()
+	e.AddBuiltinFunctions(map[string]*object.Builtin{
+		"soft": {
+			Name:       "soft",
+			ArgTypes:   object.ArgTypes{},
+			ReturnType: object.TypeVoid,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				return nil, BuiltinFuncError("target is lost")
+			},
+		},
+		"hard": {
+			Name:       "hard",
+			ArgTypes:   object.ArgTypes{},
+			ReturnType: object.TypeVoid,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				return nil, &FatalError{Err: BuiltinFuncError("connection lost")}
+			},
+		},
+	})
+	err = e.ExecAst(astProgram, env)
+	require.NotNil(t, err)
+	assert.True(t, IsFatal(err))
+
+	varR, _ := env.Get("r")
+	assert.Equal(t, int64(1), varR.(*object.Integer).Value)
+}
+
+func TestAssert(t *testing.T) {
+	input := `dist = -1.
+assert dist > 0., "negative distance"
+dist = 1.
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	err = NewExecAstVisitor().ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	require.IsType(t, &AssertionError{}, err)
+	assertionErr, _ := err.(*AssertionError)
+	assert.Equal(t, "dist > 0.", assertionErr.Condition)
+	assert.Equal(t, "negative distance", assertionErr.Message)
+	assert.Equal(t, 2, assertionErr.Line)
+	assert.Equal(t, 1, assertionErr.Col)
+
+	var logged []*AssertionError
+	e := NewExecAstVisitor()
+	e.SetAssertMode(AssertLog)
+	e.SetAssertLogger(func(err *AssertionError) { logged = append(logged, err) })
+	env := object.NewEnvironment()
+	err = e.ExecAst(astProgram, env)
+	require.Nil(t, err)
+	require.Len(t, logged, 1)
+	varDist, _ := env.Get("dist")
+	assert.Equal(t, 1., varDist.(*object.Float).Value)
+
+	e = NewExecAstVisitor()
+	e.SetAssertMode(AssertStrip)
+	var operations []Operation
+	e.SetExecCallback(func(operation Operation) { operations = append(operations, operation) })
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.Nil(t, err)
+	for _, operation := range operations {
+		assert.NotEqual(t, AssertStmt, operation.Type)
+	}
+}
+
+func TestTypeDefinitions(t *testing.T) {
+	input := `type Angle float
+type Distance float
+type Speed = float
+struct mech {
+   Angle angle
+   Speed speed
+}
+rotate = fn(Angle a, Angle b) Angle {
+   return a + b
+}
+a = rotate(Angle(1.5), Angle(0.5))
+m = mech{angle = a, speed = 2.}
+m.speed = 3.
+m.angle = -a
+s = m.speed * 2.
+f = float(m.angle) + s
+d = Distance(3)
+i = int(2.7)
+e = ?Angle
+speeds = []Speed{1., 2.}
+`
+	env := testExecAngGetEnv(t, input)
+
+	varA, ok := env.Get("a")
+	require.True(t, ok)
+	require.IsType(t, &object.Float{}, varA)
+	assert.Equal(t, "Angle", string(varA.Type()))
+	assert.Equal(t, 2., varA.(*object.Float).Value)
+
+	varM, _ := env.Get("m")
+	assert.Equal(t, "Angle", string(varM.(*object.Struct).Fields["angle"].Type()))
+
+	for name, expected := range map[string]string{
+		"s":      "float",
+		"f":      "float",
+		"d":      "Distance",
+		"i":      "int",
+		"e":      "Angle",
+		"speeds": "[]float",
+	} {
+		v, ok := env.Get(name)
+		require.True(t, ok, name)
+		assert.Equal(t, expected, string(v.Type()), name)
+	}
+	varF, _ := env.Get("f")
+	assert.Equal(t, 4., varF.(*object.Float).Value)
+	varI, _ := env.Get("i")
+	assert.Equal(t, int64(2), varI.(*object.Integer).Value)
+}
+
+func TestTypeAliasOfStructAndEnum(t *testing.T) {
+	input := `struct point {
+   float x
+   float y
+}
+enum Colors {red, green, blue}
+type P = point
+type C = Colors
+p = P{x = 1., y = 2.}
+q = point{x = 3., y = 4.}
+q = p
+e = ?P
+a = C:green
+b = if a == Colors:green { C:red } else { Colors:blue }
+f = fn(P p, C c) float {
+   return p.x
+}
+x = f(q, a)
+`
+	env := testExecAngGetEnv(t, input)
+
+	for name, expected := range map[string]string{
+		"p": "point",
+		"e": "point",
+		"a": "Colors",
+		"b": "Colors",
+	} {
+		v, ok := env.Get(name)
+		require.True(t, ok, name)
+		assert.Equal(t, expected, string(v.Type()), name)
+	}
+	varA, _ := env.Get("a")
+	assert.Equal(t, "green", varA.Inspect())
+	varB, _ := env.Get("b")
+	assert.Equal(t, "red", varB.Inspect())
+	varX, _ := env.Get("x")
+	assert.Equal(t, 1., varX.(*object.Float).Value)
+}
+
+func TestTypeDefinitionsNegative(t *testing.T) {
+	for _, input := range []string{
+		"type Angle float\na = Angle(1.) + 1.\n",
+		"type Angle float\nf = fn(Angle a) float {\nreturn 1.\n}\nb = f(1.)\n",
+		"type Angle float\nstruct mech {\nAngle angle\n}\nm = mech{angle = 1.}\n",
+		"type Angle float\nstruct mech {\nAngle angle\n}\nm = mech{angle = Angle(1.)}\nm.angle = 2.\n",
+		"type Angle float\nb = absFloat(Angle(1.))\n",
+		"type Flag bool\n",
+		"type Angle float\ntype Angle int\n",
+		"type A = unknown\n",
+	} {
+		l := lexer.New(input)
+		p, err := parser.New(l)
+		require.Nil(t, err, input)
+		astProgram, err := p.Parse()
+		require.Nil(t, err, input)
+		err = NewExecAstVisitor().ExecAst(astProgram, object.NewEnvironment())
+		require.NotNil(t, err, input)
+	}
 }
 
 func TestArrayOfInt(t *testing.T) {
@@ -612,6 +1057,8 @@ b = a[1]
 
 func TestExecSwitch(t *testing.T) {
 	input := `a = 10
+r = 0
+r1 = 0
 switch {
 case a > 20
    r = 1
@@ -651,6 +1098,8 @@ default
 
 func TestExecSwitchWithParam(t *testing.T) {
 	input := `a = 10
+r = 0
+r1 = 0
 switch a {
 case > 20
    r = 1
@@ -702,6 +1151,1083 @@ func TestExecAssignmentToBuiltinShouldFail(t *testing.T) {
 	require.NotNil(t, err)
 }
 
+func TestStructFieldsAndVarsKeepDeclarationOrder(t *testing.T) {
+	input := `struct point {
+   float z
+   float y
+   float x
+   float w
+}
+p = point{x = 1., w = 2., y = 3., z = 4.}
+b = 2
+a = 1
+`
+	env := testExecAngGetEnv(t, input)
+
+	varP, ok := env.Get("p")
+	require.True(t, ok)
+	assert.Equal(t, "point{z: 4.00, y: 3.00, x: 1.00, w: 2.00}", varP.Inspect())
+
+	def, ok := env.GetStructDefinition("point")
+	require.True(t, ok)
+	assert.Equal(t, []string{"z", "y", "x", "w"}, def.FieldNames())
+
+	assert.Equal(t, []string{"p", "b", "a"}, env.Keys())
+
+	json, err := env.GetVarsAsJson()
+	require.Nil(t, err)
+	assert.Equal(t, `{"p":"point{z: 4.00, y: 3.00, x: 1.00, w: 2.00}","b":"2","a":"1"}`, string(json))
+}
+
+func TestConst(t *testing.T) {
+	input := `const LIMIT = 10 * 2
+a = LIMIT + 1
+`
+	env := testExecAngGetEnv(t, input)
+
+	varA, ok := env.Get("a")
+	require.True(t, ok)
+	require.IsType(t, &object.Integer{}, varA)
+	assert.Equal(t, int64(21), varA.(*object.Integer).Value)
+	assert.True(t, env.IsConst("LIMIT"))
+}
+
+func TestAssignmentToHostConstNegative(t *testing.T) {
+	input := `PI = 3.
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	env := object.NewEnvironment()
+	env.SetConst("PI", &object.Float{Value: 3.14})
+	err = NewExecAstVisitor().ExecAst(astProgram, env)
+	require.NotNil(t, err)
+
+	varPI, _ := env.Get("PI")
+	assert.Equal(t, 3.14, varPI.(*object.Float).Value)
+}
+
+func TestConstShadowedByArgument(t *testing.T) {
+	input := `const LIMIT = 5
+f = fn(int LIMIT) int {
+   LIMIT = LIMIT + 1
+   return LIMIT
+}
+a = f(1)
+`
+	env := testExecAngGetEnv(t, input)
+
+	varA, ok := env.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, int64(2), varA.(*object.Integer).Value)
+	varLimit, ok := env.Get("LIMIT")
+	require.True(t, ok)
+	assert.Equal(t, int64(5), varLimit.(*object.Integer).Value)
+}
+
+func TestAssignmentToConstInBlockNegative(t *testing.T) {
+	input := `const LIMIT = 5
+if true {
+   LIMIT = 6
+}
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	env := object.NewEnvironment()
+	err = NewExecAstVisitor().ExecAst(astProgram, env)
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.AssignmentToConst, diagErr.Code)
+	assert.Equal(t, 3, diagErr.Line)
+
+	varLimit, _ := env.Get("LIMIT")
+	assert.Equal(t, int64(5), varLimit.(*object.Integer).Value)
+}
+
+func TestHostUpdatesConst(t *testing.T) {
+	input := `a = PI * 2.
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	env := object.NewEnvironment()
+	env.SetConst("PI", &object.Float{Value: 3.})
+	env.Set("PI", &object.Float{Value: 3.14})
+	assert.True(t, env.IsConst("PI"))
+	require.Nil(t, NewExecAstVisitor().ExecAst(astProgram, env))
+
+	varA, _ := env.Get("a")
+	assert.Equal(t, 6.28, varA.(*object.Float).Value)
+}
+
+func TestHostConstInConstExpression(t *testing.T) {
+	input := `const TWO_PI = PI * 2.
+const BIG = LIMIT > 10
+`
+	env := object.NewEnvironment()
+	env.SetConst("PI", &object.Float{Value: 3.14})
+	env.SetConst("LIMIT", &object.Integer{Value: 20})
+	env.SetConst("mech", &object.Struct{Definition: &object.StructDefinition{Name: "mech"}})
+
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	p.SetConsts(env.Consts())
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+	require.Nil(t, NewExecAstVisitor().ExecAst(astProgram, env))
+
+	varTwoPi, _ := env.Get("TWO_PI")
+	assert.Equal(t, 6.28, varTwoPi.(*object.Float).Value)
+	varBig, _ := env.Get("BIG")
+	assert.True(t, varBig.(*object.Boolean).Value)
+
+	// without host consts parser can't fold the expression, struct consts can't be folded at all
+	for input, constName := range map[string]string{
+		"const TWO_PI = PI * 2.\n": "PI",
+		"const M = mech\n":         "mech",
+	} {
+		l = lexer.New(input)
+		p, err = parser.New(l)
+		require.Nil(t, err)
+		if constName == "mech" {
+			p.SetConsts(env.Consts())
+		}
+		_, err = p.Parse()
+		require.NotNil(t, err, input)
+		assert.Equal(t, diag.NotConst, err.(diag.List)[0].Code, input)
+	}
+}
+
+func TestReadOnlyHostBindingsNegative(t *testing.T) {
+	for _, input := range []string{
+		"mech = ?mech\n",
+		"mech.x = 0.\n",
+		"m = mech\nm.x = 0.\n",
+		"mech.p.x = 0.\n",
+	} {
+		l := lexer.New(input)
+		p, err := parser.New(l)
+		require.Nil(t, err, input)
+		astProgram, err := p.Parse()
+		require.Nil(t, err, input)
+
+		env := object.NewEnvironment()
+		pointDef := &object.StructDefinition{Name: "point", Fields: map[string]string{"x": "float"}}
+		mechDef := &object.StructDefinition{Name: "mech", Fields: map[string]string{"x": "float", "p": "point"}}
+		require.Nil(t, env.RegisterStructDefinition(pointDef))
+		require.Nil(t, env.RegisterStructDefinition(mechDef))
+		point, err := env.LoadVarsInStruct(pointDef, map[string]interface{}{"x": 1.})
+		require.Nil(t, err)
+		mech, err := env.LoadVarsInStruct(mechDef, map[string]interface{}{"x": 2., "p": point})
+		require.Nil(t, err)
+		env.SetReadOnly("mech", mech)
+
+		err = NewExecAstVisitor().ExecAst(astProgram, env)
+		require.NotNil(t, err, input)
+		assert.Equal(t, 2., mech.Fields["x"].(*object.Float).Value, input)
+		assert.Equal(t, 1., point.Fields["x"].(*object.Float).Value, input)
+	}
+}
+
+func TestReadOnlyShadowedByArgument(t *testing.T) {
+	input := `f = fn(int mech) int {
+   mech = mech + 5
+   return mech
+}
+a = f(1)
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	env := object.NewEnvironment()
+	env.SetReadOnly("mech", &object.Float{Value: 2.})
+	require.Nil(t, NewExecAstVisitor().ExecAst(astProgram, env))
+
+	varA, ok := env.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, int64(6), varA.(*object.Integer).Value)
+	assert.True(t, env.IsReadOnly("mech"))
+}
+
+func TestReadOnlyStructField(t *testing.T) {
+	l := lexer.New("commands.move = 1.\n")
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	env := object.NewEnvironment()
+	def := &object.StructDefinition{Name: "commands", Fields: map[string]string{"move": "float", "id": "int"}}
+	commands, err := env.LoadVarsInStruct(def, map[string]interface{}{"move": 0., "id": 5})
+	require.Nil(t, err)
+	commands.MarkFieldReadOnly("id")
+	env.Set("commands", commands)
+
+	err = NewExecAstVisitor().ExecAst(astProgram, env)
+	require.Nil(t, err)
+	assert.Equal(t, 1., commands.Fields["move"].(*object.Float).Value)
+
+	l = lexer.New("commands.id = 1\n")
+	p, err = parser.New(l)
+	require.Nil(t, err)
+	astProgram, err = p.Parse()
+	require.Nil(t, err)
+	err = NewExecAstVisitor().ExecAst(astProgram, env)
+	require.NotNil(t, err)
+	assert.Equal(t, int64(5), commands.Fields["id"].(*object.Integer).Value)
+}
+
+func TestFunctionMutatesOuterVariable(t *testing.T) {
+	input := `counter = 0
+x = 10
+inc = fn(int x) int {
+   counter = counter + x
+   tmp = counter * 2
+   return tmp
+}
+a = inc(2)
+b = inc(3)
+`
+	env := testExecAngGetEnv(t, input)
+
+	varCounter, ok := env.Get("counter")
+	require.True(t, ok)
+	assert.Equal(t, int64(5), varCounter.(*object.Integer).Value)
+
+	varB, ok := env.Get("b")
+	require.True(t, ok)
+	assert.Equal(t, int64(10), varB.(*object.Integer).Value)
+
+	varX, ok := env.Get("x")
+	require.True(t, ok)
+	assert.Equal(t, int64(10), varX.(*object.Integer).Value, "argument should shadow outer var explicitly")
+
+	_, ok = env.Get("tmp")
+	assert.False(t, ok, "function local var should not leak")
+}
+
+func TestMultiLineExpressions(t *testing.T) {
+	input := `struct point {
+   float x
+   float y
+}
+sum = fn(int x, int y) int {
+   return x + y
+}
+a = sum(
+   2,
+   5,
+)
+arr = []int{
+   1,
+   2,
+   3,
+}
+p = point{
+   x = 1.,
+   y = 2.,
+}
+b = (a +
+   arr[1]
+   * 2)
+c = sum(fn(int x) int {
+   return x
+}(1), 2)
+`
+	env := testExecAngGetEnv(t, input)
+
+	varA, ok := env.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, int64(7), varA.(*object.Integer).Value)
+
+	varArr, ok := env.Get("arr")
+	require.True(t, ok)
+	assert.Len(t, varArr.(*object.Array).Elements, 3)
+
+	varP, ok := env.Get("p")
+	require.True(t, ok)
+	assert.Equal(t, 1., varP.(*object.Struct).Fields["x"].(*object.Float).Value)
+
+	varB, ok := env.Get("b")
+	require.True(t, ok)
+	assert.Equal(t, int64(11), varB.(*object.Integer).Value)
+
+	varC, ok := env.Get("c")
+	require.True(t, ok)
+	assert.Equal(t, int64(3), varC.(*object.Integer).Value)
+}
+
+func TestBinExpressionLeftAssociativity(t *testing.T) {
+	input := `a = 1 - 2 * 3 - 4
+b = 20 / 2 / 5
+c = 2 * 3 + 4 * 5 - 6
+`
+	env := testExecAngGetEnv(t, input)
+
+	varA, _ := env.Get("a")
+	assert.Equal(t, int64(-9), varA.(*object.Integer).Value)
+	varB, _ := env.Get("b")
+	assert.Equal(t, int64(2), varB.(*object.Integer).Value)
+	varC, _ := env.Get("c")
+	assert.Equal(t, int64(20), varC.(*object.Integer).Value)
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	input := `fact = fn(int n) int {
+   if n < 2 {
+      return 1
+   }
+   return n * fact(n - 1)
+}
+calc = fn(int n) int {
+   return fact(n)
+}
+r = 0
+try {
+   r = calc(5)
+} recover {
+   r = -1
+}
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	env := object.NewEnvironment()
+	e := NewExecAstVisitor()
+	e.SetMaxCallDepth(6)
+	err = e.ExecAst(astProgram, env)
+	require.Nil(t, err)
+	varR, _ := env.Get("r")
+	assert.Equal(t, int64(120), varR.(*object.Integer).Value)
+
+	e.SetMaxCallDepth(5)
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	assert.True(t, IsFatal(err), "call depth error should not be recovered")
+
+	var depthErr *MaxCallDepthError
+	require.True(t, errors.As(err, &depthErr))
+	assert.Equal(t, 5, depthErr.MaxDepth)
+	assert.Equal(t, []string{"calc", "fact", "fact", "fact", "fact", "fact"}, depthErr.CallChain)
+	assert.Equal(t, 5, depthErr.Line)
+	assert.Contains(t, err.Error(), "calc -> fact x5")
+}
+
+func TestEndlessRecursionNegative(t *testing.T) {
+	input := `f = fn(int n) int {
+   return f(n + 1)
+}
+r = f(0)
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	err = NewExecAstVisitor().ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	var depthErr *MaxCallDepthError
+	require.True(t, errors.As(err, &depthErr))
+	assert.Len(t, depthErr.CallChain, DefaultMaxCallDepth+1)
+}
+
+func TestMaxCallDepthUnlimited(t *testing.T) {
+	input := `f = fn(int n) int {
+   if n == 0 {
+      return 0
+   }
+   return f(n - 1) + 1
+}
+r = f(1500)
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	for _, depth := range []int{0, -1} {
+		env := object.NewEnvironment()
+		e := NewExecAstVisitor()
+		e.SetMaxCallDepth(depth)
+		require.Nil(t, e.ExecAst(astProgram, env), "max call depth %d", depth)
+		varR, _ := env.Get("r")
+		assert.Equal(t, int64(1500), varR.(*object.Integer).Value, "max call depth %d", depth)
+	}
+
+	// without call depth limit recursion is bounded by gas limit
+	e := NewExecAstVisitor()
+	e.SetMaxCallDepth(0)
+	e.SetGasLimit(10000)
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	var gasErr *OutOfGasError
+	require.True(t, errors.As(err, &gasErr))
+
+	e.SetGasLimit(-1)
+	require.Nil(t, e.ExecAst(astProgram, object.NewEnvironment()))
+
+	// host can't lift the hard limit, endless recursion doesn't overflow Go stack
+	input = `f = fn(int n) int {
+   return f(n + 1)
+}
+r = f(0)
+`
+	l = lexer.New(input)
+	p, err = parser.New(l)
+	require.Nil(t, err)
+	astProgram, err = p.Parse()
+	require.Nil(t, err)
+	for _, depth := range []int{0, MaxCallDepthLimit * 10} {
+		e.SetMaxCallDepth(depth)
+		err = e.ExecAst(astProgram, object.NewEnvironment())
+		var depthErr *MaxCallDepthError
+		require.True(t, errors.As(err, &depthErr), "max call depth %d", depth)
+		assert.Equal(t, MaxCallDepthLimit, depthErr.MaxDepth, "max call depth %d", depth)
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	input := `div = fn(int a, int b) int {
+   return a / b
+}
+half = fn(int a) int {
+   return div(a, 0)
+}
+calc = half
+apply = fn(int a) int {
+   return calc(a)
+}
+r = 0
+try {
+   r = apply(4)
+} recover err {
+   r = -1
+}
+r = apply(4)
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	env := object.NewEnvironment()
+	err = NewExecAstVisitor().ExecAst(astProgram, env)
+	require.NotNil(t, err)
+
+	var traced *StackTraceError
+	require.True(t, errors.As(err, &traced))
+	assert.Equal(t, []StackFrame{
+		{FuncName: "div", Line: 5, Col: 14},
+		{FuncName: "half", Line: 9, Col: 15},
+		{FuncName: "apply", Line: 17, Col: 10},
+	}, traced.Stack)
+	assert.Contains(t, err.Error(), "line:2, pos 13\nstack trace:\n   div called at line:5, pos 14")
+
+	input = `f = fn() int {
+   return check(1)
+}
+r = f()
+`
+	l = lexer.New(input)
+	p, err = parser.New(l)
+	require.Nil(t, err)
+	astProgram, err = p.Parse()
+	require.Nil(t, err)
+
+	e := NewExecAstVisitor()
+	e.AddBuiltinFunctions(map[string]*object.Builtin{
+		"check": {
+			Name:       "check",
+			ArgTypes:   object.ArgTypes{object.TypeInt},
+			ReturnType: object.TypeInt,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				return nil, &FatalError{Err: BuiltinFuncError("check failed")}
+			},
+		},
+	})
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	require.True(t, errors.As(err, &traced))
+	assert.Equal(t, []StackFrame{
+		{FuncName: "check", Line: 2, Col: 16},
+		{FuncName: "f", Line: 4, Col: 6},
+	}, traced.Stack)
+	assert.True(t, IsFatal(err))
+}
+
+func TestRecoveredErrorHasNoStackTrace(t *testing.T) {
+	input := `f = fn() int {
+   return 1 / 0
+}
+try {
+   r = f()
+} recover err {
+   report(err)
+}
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	var reported string
+	e := NewExecAstVisitor()
+	e.AddBuiltinFunctions(map[string]*object.Builtin{
+		"report": {
+			Name:       "report",
+			ArgTypes:   object.ArgTypes{object.TypeError},
+			ReturnType: object.TypeVoid,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				reported = args[0].(*object.Error).Message
+				return &object.Void{}, nil
+			},
+		},
+	})
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.Nil(t, err)
+	assert.Contains(t, reported, "line:2, pos 13")
+	assert.NotContains(t, reported, "stack trace")
+}
+
+func TestRuntimeErrorIsStructured(t *testing.T) {
+	input := `a = 1
+b = a + 1.
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	err = NewExecAstVisitor().ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.PhaseRuntime, diagErr.Phase)
+	assert.Equal(t, diag.OperandTypesMismatch, diagErr.Code)
+	assert.Equal(t, 2, diagErr.Line)
+	assert.Equal(t, 7, diagErr.Col)
+	assert.Equal(t, 2, diagErr.EndLine)
+	assert.Equal(t, 8, diagErr.EndCol)
+}
+
+func TestBuiltinErrorHasPosition(t *testing.T) {
+	input := `try {
+   a = soft()
+} recover {
+}
+b = hard()
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	hostErr := errors.New("connection lost")
+	e := NewExecAstVisitor()
+	e.AddBuiltinFunctions(map[string]*object.Builtin{
+		"soft": {
+			Name:       "soft",
+			ArgTypes:   object.ArgTypes{},
+			ReturnType: object.TypeInt,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				return nil, BuiltinFuncError("target is lost")
+			},
+		},
+		"hard": {
+			Name:       "hard",
+			ArgTypes:   object.ArgTypes{},
+			ReturnType: object.TypeInt,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				return nil, hostErr
+			},
+		},
+	})
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.BuiltinFailed, diagErr.Code)
+	assert.Equal(t, 5, diagErr.Line)
+	assert.Equal(t, 9, diagErr.Col)
+	assert.True(t, errors.Is(err, hostErr))
+	assert.Contains(t, err.Error(), "connection lost\nline:5, pos 9")
+}
+
+func TestBuiltinNilResultNegative(t *testing.T) {
+	input := `a = 1
+b = broken()
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	e := NewExecAstVisitor()
+	e.AddBuiltinFunctions(map[string]*object.Builtin{
+		"broken": {
+			Name:       "broken",
+			ArgTypes:   object.ArgTypes{},
+			ReturnType: object.TypeInt,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				return nil, nil
+			},
+		},
+	})
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.ReturnTypeMismatch, diagErr.Code)
+	assert.Equal(t, 2, diagErr.Line)
+	assert.Equal(t, 11, diagErr.Col)
+}
+
+func TestDidYouMeanSuggestions(t *testing.T) {
+	tests := []struct {
+		input       string
+		suggestions []string
+		message     string
+	}{
+		{
+			input: `xelon = 1
+xenon = 2
+a = xelno + 1
+`,
+			suggestions: []string{"xelon"},
+			message:     "identifier not found: xelno. Did you mean 'xelon'?",
+		},
+		{
+			input: `a = asbFloat(-1.)
+`,
+			suggestions: []string{"absFloat"},
+			message:     "identifier not found: asbFloat. Did you mean 'absFloat'?",
+		},
+		{
+			input: `struct point {
+   float x
+   float y
+   float angle
+}
+p = point{x = 1., y = 2., angle = 0.}
+a = p.angel
+`,
+			suggestions: []string{"angle"},
+			message:     "Struct 'point' doesn't have field 'angel'. Did you mean 'angle'?",
+		},
+		{
+			input: `enum ObjectTypes {xelon, spore}
+a = ObjectTypes:spor
+`,
+			suggestions: []string{"spore"},
+			message:     "Enum 'ObjectTypes' doesn't have element 'spor'. Did you mean 'spore'?",
+		},
+		{
+			input: `a = 1
+b = qwerty
+`,
+			suggestions: nil,
+			message:     "identifier not found: qwerty",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := parser.New(l)
+		require.Nil(t, err)
+		astProgram, err := p.Parse()
+		require.Nil(t, err)
+
+		err = NewExecAstVisitor().ExecAst(astProgram, object.NewEnvironment())
+		require.NotNil(t, err)
+		var diagErr *diag.Error
+		require.True(t, errors.As(err, &diagErr))
+		assert.Equal(t, tt.suggestions, diagErr.Suggestions)
+		assert.Equal(t, tt.message, diagErr.Message)
+	}
+}
+
+func TestRuntimeErrorsLocale(t *testing.T) {
+	input := `xelon = 1
+try {
+   a = 1 / (xelon - 1)
+} recover err {
+   report(err)
+}
+b = xelno
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	var reported string
+	e := NewExecAstVisitor()
+	e.SetLocale(diag.LocaleRu)
+	e.AddBuiltinFunctions(map[string]*object.Builtin{
+		"report": {
+			Name:       "report",
+			ArgTypes:   object.ArgTypes{object.TypeError},
+			ReturnType: object.TypeVoid,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				reported = args[0].(*object.Error).Message
+				return &object.Void{}, nil
+			},
+		},
+	})
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.IdentifierNotFound, diagErr.Code)
+	assert.Equal(t, "идентификатор не найден: xelno. Возможно, имелось в виду 'xelon'?", diagErr.Message)
+	assert.Contains(t, reported, "деление на ноль")
+}
+
+func TestStackTraceLocale(t *testing.T) {
+	input := `f = fn(int a) int {
+   return a / 0
+}
+r = f(1)
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	e := NewExecAstVisitor()
+	e.SetLocale(diag.LocaleRu)
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	assert.Equal(t, "деление на ноль\nстрока:2, поз 13\nстэк вызовов:\n   f вызвана в строке:4, поз 6", err.Error())
+}
+
+func TestIntegerDivisionByZero(t *testing.T) {
+	input := `a = 0
+try {
+   a = 10 / a
+} recover {
+   a = -1
+}
+b = 1 / 0
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	env := object.NewEnvironment()
+	err = NewExecAstVisitor().ExecAst(astProgram, env)
+	require.NotNil(t, err)
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.DivisionByZero, diagErr.Code)
+	assert.Equal(t, 7, diagErr.Line)
+
+	varA, _ := env.Get("a")
+	assert.Equal(t, int64(-1), varA.(*object.Integer).Value)
+}
+
+func TestPanicInBuiltinIsInternalError(t *testing.T) {
+	input := `f = fn() int {
+   return crash()
+}
+a = 1
+b = f()
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	e := NewExecAstVisitor()
+	e.AddBuiltinFunctions(map[string]*object.Builtin{
+		"crash": {
+			Name:       "crash",
+			ReturnType: object.TypeInt,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				var arr []int
+				return &object.Integer{Value: int64(arr[1])}, nil
+			},
+		},
+	})
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.Internal, diagErr.Code)
+	assert.Contains(t, diagErr.Message, "index out of range")
+	assert.Equal(t, 2, diagErr.Line)
+
+	var traced *StackTraceError
+	require.True(t, errors.As(err, &traced))
+	assert.Equal(t, "f", traced.Stack[len(traced.Stack)-1].FuncName)
+
+	p, err = parser.New(lexer.New("c = 1\n"))
+	require.Nil(t, err)
+	astProgram, err = p.Parse()
+	require.Nil(t, err)
+	require.Nil(t, e.ExecAst(astProgram, object.NewEnvironment()), "visitor should work after panic")
+}
+
+func TestLoadVarsInStructUnsupportedType(t *testing.T) {
+	env := object.NewEnvironment()
+	def := &object.StructDefinition{Name: "mech", Fields: map[string]string{"name": "string"}}
+	_, err := env.LoadVarsInStruct(def, map[string]interface{}{"name": "xelon"})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "'string'")
+}
+
+func TestNumericPolicy(t *testing.T) {
+	tests := []struct {
+		input string
+		code  diag.Code
+	}{
+		{"a = 9223372036854775807 + 1\n", diag.IntOverflow},
+		{"m = -9223372036854775807\na = m - 2\n", diag.IntOverflow},
+		{"a = 4611686018427387904 * 2\n", diag.IntOverflow},
+		{"m = -9223372036854775807 - 1\na = m / -1\n", diag.IntOverflow},
+		{"m = -9223372036854775807 - 1\na = -m\n", diag.IntOverflow},
+		{"a = 1. / 0.\n", diag.DivisionByZero},
+		{"z = 0.\na = 1. - 1.\n", ""},
+		{"struct point {\n   float x\n}\nz = 0.\np = point{x = 0.}\np.x = z * (1. / 0.)\n", diag.DivisionByZero},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := parser.New(l)
+		require.Nil(t, err, tt.input)
+		astProgram, err := p.Parse()
+		require.Nil(t, err, tt.input)
+
+		err = NewExecAstVisitor().ExecAst(astProgram, object.NewEnvironment())
+		require.Nil(t, err, "checks should be off by default: %s", tt.input)
+
+		e := NewExecAstVisitor()
+		e.SetNumericPolicy(StrictNumericPolicy)
+		err = e.ExecAst(astProgram, object.NewEnvironment())
+		if tt.code == "" {
+			require.Nil(t, err, tt.input)
+			continue
+		}
+		require.NotNil(t, err, tt.input)
+		var diagErr *diag.Error
+		require.True(t, errors.As(err, &diagErr), tt.input)
+		assert.Equal(t, tt.code, diagErr.Code, tt.input)
+	}
+}
+
+func TestNotFiniteValueOnAssignment(t *testing.T) {
+	input := `struct point {
+   float x
+}
+zero = 0.
+inf = 1. / zero
+p = point{x = 1.}
+p.x = inf - inf
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	e := NewExecAstVisitor()
+	e.SetNumericPolicy(NumericPolicy{CheckNotFinite: true})
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.NotFiniteValue, diagErr.Code)
+	assert.Equal(t, "Value assigned to 'inf' is not a finite number: +Inf", diagErr.Message)
+	assert.Equal(t, 5, diagErr.Line)
+
+	e.SetNumericPolicy(NumericPolicy{})
+	env := object.NewEnvironment()
+	require.Nil(t, e.ExecAst(astProgram, env))
+	varP, _ := env.Get("p")
+	assert.True(t, math.IsNaN(varP.(*object.Struct).Fields["x"].(*object.Float).Value))
+}
+
+func TestOutOfGas(t *testing.T) {
+	input := `f = fn(int n) int {
+   try {
+      return f(n + 1)
+   } recover {
+      return -1
+   }
+   return 0
+}
+r = f(0)
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	e := NewExecAstVisitor()
+	e.SetGasLimit(100)
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	assert.True(t, IsFatal(err), "out of gas should not be recovered")
+
+	var gasErr *OutOfGasError
+	require.True(t, errors.As(err, &gasErr))
+	assert.Equal(t, int64(100), gasErr.GasLimit)
+	assert.True(t, gasErr.GasUsed > 100)
+	assert.Equal(t, gasErr.GasUsed, e.GasUsed())
+	assert.Equal(t, 3, gasErr.Line)
+
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.OutOfGas, diagErr.Code)
+}
+
+func TestGasCosts(t *testing.T) {
+	input := `a = absInt(-5)
+b = a + 1
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	e := NewExecAstVisitor()
+	e.SetGasCosts(GasCosts{
+		Operations: map[OperationType]int64{Builtin: 2, BinExpr: 3},
+		Builtins:   map[string]int64{BuiltinAbsInt: 10},
+	})
+	require.Nil(t, e.ExecAst(astProgram, object.NewEnvironment()))
+	assert.Equal(t, int64(15), e.GasUsed())
+
+	e.SetGasLimit(14)
+	err = e.ExecAst(astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	var gasErr *OutOfGasError
+	require.True(t, errors.As(err, &gasErr))
+	assert.Equal(t, 2, gasErr.Line)
+
+	e.SetGasLimit(0)
+	require.Nil(t, e.ExecAst(astProgram, object.NewEnvironment()))
+}
+
+func TestExecAstContextCancel(t *testing.T) {
+	input := `a = 1
+stop()
+b = 2
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e := NewExecAstVisitor()
+	e.AddBuiltinFunctions(map[string]*object.Builtin{
+		"stop": {
+			Name:       "stop",
+			ReturnType: object.TypeVoid,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				cancel()
+				return &object.Void{}, nil
+			},
+		},
+	})
+	env := object.NewEnvironment()
+	err = e.ExecAstContext(ctx, astProgram, env)
+	require.NotNil(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, IsFatal(err))
+
+	var cancelledErr *CancelledError
+	require.True(t, errors.As(err, &cancelledErr))
+	assert.Equal(t, 3, cancelledErr.Line)
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.ExecutionCancelled, diagErr.Code)
+
+	_, ok := env.Get("a")
+	assert.True(t, ok)
+	_, ok = env.Get("b")
+	assert.False(t, ok, "execution should stop after cancellation")
+
+	// cancelled context doesn't affect next executions without it
+	require.Nil(t, e.ExecAst(astProgram, object.NewEnvironment()))
+}
+
+func TestExecAstContextDeadlineInBuiltin(t *testing.T) {
+	input := `try {
+   wait()
+} recover {
+   a = 1
+}
+`
+	l := lexer.New(input)
+	p, err := parser.New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	e := NewExecAstVisitor()
+	e.AddBuiltinFunctions(map[string]*object.Builtin{
+		"wait": {
+			Name:       "wait",
+			ReturnType: object.TypeVoid,
+			Fn: func(ctx context.Context, env *object.Environment, args []object.Object) (object.Object, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = e.ExecAstContext(ctx, astProgram, object.NewEnvironment())
+	require.NotNil(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, IsFatal(err), "deadline should not be recovered")
+
+	var cancelledErr *CancelledError
+	require.True(t, errors.As(err, &cancelledErr))
+	assert.Equal(t, 2, cancelledErr.Line)
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.DeadlineExceeded, diagErr.Code)
+}
+
 func testExecAngGetEnv(t *testing.T, input string) *object.Environment {
 	l := lexer.New(input)
 	p, err := parser.New(l)
@@ -720,11 +2246,13 @@ const codeToBench = `sum = fn(int x, int y) int {
 }
 a = sum(2, 5)
 c = 10
+bb = 0
 if c > 8 {
     bb = 1
 } else {
     bb = 2
 }
+f = 0.
 switch bb {
 case == 1
    f = 2.
@@ -0,0 +1,59 @@
+package interpereter
+
+import (
+	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
+	"github.com/justclimber/marslang/object"
+
+	"math"
+)
+
+// NumericPolicy configures safety checks of arithmetic. All checks are off by default:
+// int overflow wraps around and float division by zero gives Inf or NaN.
+// Int division by zero is always an error
+type NumericPolicy struct {
+	// int overflow in arithmetic operations and unary minus is a runtime error
+	CheckIntOverflow bool
+	// float division by zero is a runtime error
+	CheckFloatDivisionByZero bool
+	// NaN and Inf can't be assigned to variables, consts and struct fields
+	CheckNotFinite bool
+}
+
+// StrictNumericPolicy enables all numeric checks
+var StrictNumericPolicy = NumericPolicy{
+	CheckIntOverflow:         true,
+	CheckFloatDivisionByZero: true,
+	CheckNotFinite:           true,
+}
+
+// SetNumericPolicy configures checks of int overflow, float division by zero and NaN/Inf values
+func (e *ExecAstVisitor) SetNumericPolicy(policy NumericPolicy) {
+	e.numericPolicy = policy
+}
+
+// checkFinite returns error if NaN or Inf value is assigned to the name and the policy forbids it.
+// Elements of arrays are checked too
+func (e *ExecAstVisitor) checkFinite(node ast.INode, name string, value object.Object) error {
+	if !e.numericPolicy.CheckNotFinite {
+		return nil
+	}
+	if notFinite, ok := findNotFinite(value); ok {
+		return runtimeError(node, diag.NotFiniteValue, name, notFinite.Inspect())
+	}
+	return nil
+}
+
+func findNotFinite(value object.Object) (*object.Float, bool) {
+	switch v := value.(type) {
+	case *object.Float:
+		return v, math.IsNaN(v.Value) || math.IsInf(v.Value, 0)
+	case *object.Array:
+		for _, el := range v.Elements {
+			if f, ok := findNotFinite(el); ok {
+				return f, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -1,50 +1,86 @@
 package interpereter
 
 import (
+	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/object"
 	"github.com/justclimber/marslang/token"
 
 	"fmt"
+	"math"
 )
 
-func execScalarBinOperation(left, right object.Object, operator string) (object.Object, error) {
-	if left.Type() == object.TypeInt {
-		left, _ := left.(*object.Integer)
-		right, _ := right.(*object.Integer)
-		result, err := integerBinOperation(left, right, operator)
-		return result, err
-	} else if left.Type() == object.TypeFloat {
-		left, _ := left.(*object.Float)
-		right, _ := right.(*object.Float)
-		result, err := floatBinOperation(left, right, operator)
+// execScalarBinOperation executes operation on operands of the same type. Result of arithmetic operation
+// on distinct types (`type Angle float`) keeps the type
+func execScalarBinOperation(
+	left, right object.Object,
+	node *ast.BinExpression,
+	policy NumericPolicy,
+) (object.Object, error) {
+	operator := node.Operator
+	switch l := left.(type) {
+	case *object.Integer:
+		r, ok := right.(*object.Integer)
+		if !ok {
+			return nil, operandTypesMismatchError(node, left, right)
+		}
+		result, err := integerBinOperation(l, r, node, policy)
+		if resultInt, ok := result.(*object.Integer); ok {
+			resultInt.Named = l.Named
+		}
 		return result, err
-	} else if left.Type() == object.TypeBool {
-		left, _ := left.(*object.Boolean)
-		right, _ := right.(*object.Boolean)
-		result, err := booleanBinOperation(left, right, operator)
+	case *object.Float:
+		r, ok := right.(*object.Float)
+		if !ok {
+			return nil, operandTypesMismatchError(node, left, right)
+		}
+		result, err := floatBinOperation(l, r, node, policy)
+		if resultFloat, ok := result.(*object.Float); ok {
+			resultFloat.Named = l.Named
+		}
 		return result, err
-	}
-	if _, ok := left.(*object.Enum); ok {
+	case *object.Boolean:
+		r, ok := right.(*object.Boolean)
+		if !ok {
+			return nil, operandTypesMismatchError(node, left, right)
+		}
+		return booleanBinOperation(l, r, node)
+	case *object.Enum:
 		if operator != token.Eq {
-			return nil, fmt.Errorf("unsupported operator '%s' for type: '%s'", operator, left.Type())
+			return nil, unsupportedOperatorError(node, left)
+		}
+		r, ok := right.(*object.Enum)
+		if !ok {
+			return nil, operandTypesMismatchError(node, left, right)
+		}
+		if l.Empty || r.Empty {
+			return nativeBooleanToBoolean(l.Empty == r.Empty), nil
 		}
-		left := left.(*object.Enum).Value
-		right := right.(*object.Enum).Value
-		return nativeBooleanToBoolean(left == right), nil
+		return nativeBooleanToBoolean(l.Value == r.Value), nil
 	}
-	return nil, fmt.Errorf("unsupported operator '%s' for type: '%s'", operator, left.Type())
+	return nil, unsupportedOperatorError(node, left)
 }
 
-func integerBinOperation(left, right *object.Integer, operator string) (object.Object, error) {
-	switch operator {
+func integerBinOperation(left, right *object.Integer, node *ast.BinExpression, policy NumericPolicy) (object.Object, error) {
+	var result int64
+	var overflow bool
+	switch node.Operator {
 	case token.Plus:
-		return &object.Integer{Value: left.Value + right.Value}, nil
+		result = left.Value + right.Value
+		overflow = (left.Value^result)&(right.Value^result) < 0
 	case token.Minus:
-		return &object.Integer{Value: left.Value - right.Value}, nil
+		result = left.Value - right.Value
+		overflow = (left.Value^right.Value)&(left.Value^result) < 0
 	case token.Slash:
-		return &object.Integer{Value: left.Value / right.Value}, nil
+		if right.Value == 0 {
+			return nil, runtimeError(node, diag.DivisionByZero)
+		}
+		result = left.Value / right.Value
+		overflow = left.Value == math.MinInt64 && right.Value == -1
 	case token.Asterisk:
-		return &object.Integer{Value: left.Value * right.Value}, nil
+		result = left.Value * right.Value
+		overflow = left.Value != 0 &&
+			(result/left.Value != right.Value || left.Value == -1 && right.Value == math.MinInt64)
 	case token.Lt:
 		return nativeBooleanToBoolean(left.Value < right.Value), nil
 	case token.Gt:
@@ -54,8 +90,12 @@ func integerBinOperation(left, right *object.Integer, operator string) (object.O
 	case token.NotEq:
 		return nativeBooleanToBoolean(left.Value != right.Value), nil
 	default:
-		return nil, fmt.Errorf("unsupported operator for types: %s %s %s", left.Type(), operator, right.Type())
+		return nil, unsupportedOperatorError(node, left)
+	}
+	if overflow && policy.CheckIntOverflow {
+		return nil, runtimeError(node, diag.IntOverflow, fmt.Sprintf("%d %s %d", left.Value, node.Operator, right.Value))
 	}
+	return &object.Integer{Value: result}, nil
 }
 
 func nativeBooleanToBoolean(value bool) *object.Boolean {
@@ -65,13 +105,16 @@ func nativeBooleanToBoolean(value bool) *object.Boolean {
 	return ReservedObjFalse
 }
 
-func floatBinOperation(left, right *object.Float, operator string) (object.Object, error) {
-	switch operator {
+func floatBinOperation(left, right *object.Float, node *ast.BinExpression, policy NumericPolicy) (object.Object, error) {
+	switch node.Operator {
 	case token.Plus:
 		return &object.Float{Value: left.Value + right.Value}, nil
 	case token.Minus:
 		return &object.Float{Value: left.Value - right.Value}, nil
 	case token.Slash:
+		if right.Value == 0 && policy.CheckFloatDivisionByZero {
+			return nil, runtimeError(node, diag.DivisionByZero)
+		}
 		return &object.Float{Value: left.Value / right.Value}, nil
 	case token.Asterisk:
 		return &object.Float{Value: left.Value * right.Value}, nil
@@ -84,12 +127,24 @@ func floatBinOperation(left, right *object.Float, operator string) (object.Objec
 	case token.NotEq:
 		return nativeBooleanToBoolean(left.Value != right.Value), nil
 	default:
-		return nil, fmt.Errorf("unsupported operator for types: %s %s %s", left.Type(), operator, right.Type())
+		return nil, unsupportedOperatorError(node, left)
 	}
 }
 
-func booleanBinOperation(left, right *object.Boolean, operator string) (object.Object, error) {
-	switch operator {
+// booleanBinOperation compares empty bools like other empty values: empty is equal only to empty.
+// Logical operators on empty bool fail like empty condition of if
+func booleanBinOperation(left, right *object.Boolean, node *ast.BinExpression) (object.Object, error) {
+	if left.Empty || right.Empty {
+		switch node.Operator {
+		case token.Eq:
+			return nativeBooleanToBoolean(left.Empty == right.Empty), nil
+		case token.NotEq:
+			return nativeBooleanToBoolean(left.Empty != right.Empty), nil
+		case token.And, token.Or:
+			return nil, runtimeError(node, diag.EmptyBoolOperand, node.Operator)
+		}
+	}
+	switch node.Operator {
 	case token.Eq:
 		return nativeBooleanToBoolean(left.Value == right.Value), nil
 	case token.NotEq:
@@ -99,6 +154,14 @@ func booleanBinOperation(left, right *object.Boolean, operator string) (object.O
 	case token.Or:
 		return nativeBooleanToBoolean(left.Value || right.Value), nil
 	default:
-		return nil, fmt.Errorf("unsupported operator for types: %s %s %s", left.Type(), operator, right.Type())
+		return nil, unsupportedOperatorError(node, left)
 	}
 }
+
+func unsupportedOperatorError(node *ast.BinExpression, left object.Object) error {
+	return runtimeError(node, diag.UnsupportedOperator, node.Operator, left.Type())
+}
+
+func operandTypesMismatchError(node *ast.BinExpression, left, right object.Object) error {
+	return runtimeError(node, diag.OperandTypesMismatch, left.Type(), right.Type())
+}
@@ -0,0 +1,106 @@
+package interpereter
+
+import (
+	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/object"
+	"github.com/justclimber/marslang/token"
+
+	"strings"
+)
+
+// inferExpressionType tries to determine type of expression without executing it.
+// Returns false if type can't be determined statically
+func (e *ExecAstVisitor) inferExpressionType(node ast.IExpression, env *object.Environment) (string, bool) {
+	t, ok := e.inferExpressionRawType(node, env)
+	if !ok {
+		return "", false
+	}
+	return env.ResolveType(t), true
+}
+
+func (e *ExecAstVisitor) inferExpressionRawType(node ast.IExpression, env *object.Environment) (string, bool) {
+	switch astNode := node.(type) {
+	case *ast.NumInt:
+		return object.TypeInt, true
+	case *ast.NumFloat:
+		return object.TypeFloat, true
+	case *ast.Boolean:
+		return object.TypeBool, true
+	case *ast.Function:
+		return object.TypeFunction, true
+	case *ast.EmptierExpression:
+		if astNode.IsArray {
+			return "[]" + astNode.Type, true
+		}
+		return astNode.Type, true
+	case *ast.Array:
+		return "[]" + astNode.ElementsType, true
+	case *ast.Struct:
+		return astNode.Ident.Value, true
+	case *ast.Identifier:
+		if _, ok := e.builtins[astNode.Value]; ok {
+			return object.TypeBuiltinFn, true
+		}
+		if val, ok := env.Get(astNode.Value); ok {
+			return string(val.Type()), true
+		}
+		return "", false
+	case *ast.EnumElementCall:
+		if ident, ok := astNode.EnumExpr.(*ast.Identifier); ok {
+			if ed, ok := env.GetEnumDefinition(env.ResolveType(ident.Value)); ok {
+				return ed.Name, true
+			}
+		}
+		return "", false
+	case *ast.UnaryExpression:
+		if astNode.Operator == token.Not {
+			return object.TypeBool, true
+		}
+		return e.inferExpressionType(astNode.Right, env)
+	case *ast.BinExpression:
+		switch astNode.Operator {
+		case token.Lt, token.Gt, token.Eq, token.NotEq, token.And, token.Or:
+			return object.TypeBool, true
+		default:
+			return e.inferExpressionType(astNode.Left, env)
+		}
+	case *ast.StructFieldCall:
+		structType, ok := e.inferExpressionType(astNode.StructExpr, env)
+		if !ok {
+			return "", false
+		}
+		definition, ok := env.GetStructDefinition(structType)
+		if !ok {
+			return "", false
+		}
+		fieldType, ok := definition.Fields[astNode.Field.Value]
+		return fieldType, ok
+	case *ast.ArrayIndexCall:
+		arrayType, ok := e.inferExpressionType(astNode.Left, env)
+		if !ok || !strings.HasPrefix(arrayType, "[]") {
+			return "", false
+		}
+		return strings.TrimPrefix(arrayType, "[]"), true
+	case *ast.FunctionCall:
+		ident, ok := astNode.Function.(*ast.Identifier)
+		if !ok {
+			return "", false
+		}
+		if isConversionType(ident.Value, env) {
+			return ident.Value, true
+		}
+		if builtin, ok := e.builtins[ident.Value]; ok {
+			return builtin.ReturnType, true
+		}
+		if fn, ok := env.Get(ident.Value); ok {
+			if fnObj, ok := fn.(*object.Function); ok {
+				return fnObj.ReturnType, true
+			}
+		}
+		return "", false
+	case *ast.IfExpression:
+		return e.inferExpressionType(astNode.PositiveBranch, env)
+	default:
+		return "", false
+	}
+}
@@ -1,11 +1,12 @@
 package lexer
 
 import (
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/token"
 
-	"errors"
-	"fmt"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
@@ -15,6 +16,11 @@ type Lexer struct {
 	nextChar     rune
 	line         int
 	pos          int
+
+	// lines of doc comment waiting for the next token
+	docLines      []string
+	isCommentLine bool
+	prevTokenType token.TokenType
 }
 
 func New(input string) *Lexer {
@@ -25,8 +31,8 @@ func New(input string) *Lexer {
 }
 
 func (l *Lexer) fetch(line, pos int) {
-	l.currChar = l.input[l.currPosition]
-	l.nextChar = l.input[l.currPosition+1]
+	l.currChar = l.peekChar(0)
+	l.nextChar = l.peekChar(1)
 	l.line = line
 	l.pos = pos
 }
@@ -36,15 +42,14 @@ func (l *Lexer) GetCurrentPosition() int {
 }
 
 func (l *Lexer) read() {
+	if l.isEOF() {
+		return
+	}
 	l.currPosition += 1
 	l.currChar = l.nextChar
-	if l.currPosition+1 >= len(l.input) {
-		l.nextChar = rune(0)
-	} else {
-		l.nextChar = l.input[l.currPosition+1]
-	}
+	l.nextChar = l.peekChar(1)
 
-	if l.currPosition+1 < len(l.input) && l.input[l.currPosition-1] == '\n' {
+	if l.input[l.currPosition-1] == '\n' {
 		l.line += 1
 		l.pos = 1
 	} else {
@@ -55,9 +60,44 @@ func (l *Lexer) read() {
 func (l *Lexer) BackToToken(t token.Token) {
 	l.currPosition = t.Pos
 	l.fetch(t.Line, t.Col)
+	l.docLines = nil
+	l.isCommentLine = false
+	l.prevTokenType = t.Type
 }
 
+// NextToken returns next token. Comments placed on their own lines directly above the token
+// are attached to it as doc comment
 func (l *Lexer) NextToken() (token.Token, error) {
+	currToken, err := l.nextToken()
+	if err != nil {
+		// skip wrong char, so lexing can be continued after error
+		l.read()
+		currToken.Type = token.Illegal
+		return currToken, err
+	}
+	currToken.EndLine, currToken.EndCol = l.line, l.pos
+	switch currToken.Type {
+	case token.EOL:
+		currToken.EndLine, currToken.EndCol = currToken.Line, currToken.Col+1
+	case token.EOF:
+		currToken.EndLine, currToken.EndCol = currToken.Line, currToken.Col
+	}
+
+	if currToken.Type == token.EOL {
+		// blank line or line with code breaks doc comment
+		if !l.isCommentLine {
+			l.docLines = nil
+		}
+		l.isCommentLine = false
+	} else {
+		currToken.Doc = strings.Join(l.docLines, "\n")
+		l.docLines = nil
+	}
+	l.prevTokenType = currToken.Type
+	return currToken, nil
+}
+
+func (l *Lexer) nextToken() (token.Token, error) {
 	var currToken token.Token
 	l.skipWhitespace()
 
@@ -65,10 +105,19 @@ func (l *Lexer) NextToken() (token.Token, error) {
 	currToken.Col = l.pos
 	currToken.Pos = l.currPosition
 
+	if l.isEOF() {
+		currToken.Type = token.EOF
+		return currToken, nil
+	}
+
+	// float with leading dot like .5
+	if l.currChar == '.' && isDigit(l.nextChar) {
+		return l.readNumberToken(currToken)
+	}
+
 	simpleTokens := []string{
 		token.Comma,
 		token.Colon,
-		token.Question,
 		token.Dot,
 		token.Plus,
 		token.Minus,
@@ -104,6 +153,19 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			currToken.Type = token.Assignment
 			currToken.Value = string(l.currChar)
 		}
+	case '?':
+		if l.nextChar == '?' {
+			currToken.Value = token.NullCoalesce
+			currToken.Type = token.NullCoalesce
+			l.read()
+		} else if l.nextChar == '.' {
+			currToken.Value = token.SafeDot
+			currToken.Type = token.SafeDot
+			l.read()
+		} else {
+			currToken.Type = token.Question
+			currToken.Value = string(l.currChar)
+		}
 	case '!':
 		if l.nextChar == '=' {
 			currToken.Value = token.NotEq
@@ -119,7 +181,7 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			currToken.Type = token.And
 			l.read()
 		} else {
-			return currToken, l.error("Unexpected one `&`. Did you mean '&&'?")
+			return currToken, l.error(diag.SingleAmpersand)
 		}
 	case '|':
 		if l.nextChar == '|' {
@@ -127,87 +189,276 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			currToken.Type = token.Or
 			l.read()
 		} else {
-			return currToken, l.error("Unexpected one `|`. Did you mean '||'?")
+			return currToken, l.error(diag.SinglePipe)
 		}
 	case '/':
 		if l.nextChar == '/' {
-			l.consumeComment()
-			return l.NextToken()
+			comment := l.consumeComment()
+			if l.prevTokenType == "" || l.prevTokenType == token.EOL {
+				l.docLines = append(l.docLines, comment)
+				l.isCommentLine = true
+			} else {
+				l.docLines = nil
+			}
+			return l.nextToken()
+		} else if l.nextChar == '*' {
+			if err := l.consumeBlockComment(); err != nil {
+				return currToken, err
+			}
+			return l.nextToken()
 		} else {
 			currToken.Value = token.Slash
 			currToken.Type = token.Slash
 		}
-	case 0:
-		currToken.Value = ""
-		currToken.Type = token.EOF
+	case '"':
+		value, err := l.readString()
+		if err != nil {
+			return currToken, err
+		}
+		currToken.Value = value
+		currToken.Type = token.String
 	default:
 		if isDigit(l.currChar) {
-			value, isInt := l.readNumber()
-			currToken.Value = value
-			if isInt {
-				currToken.Type = token.NumInt
-			} else {
-				currToken.Type = token.NumFloat
-			}
-		} else if unicode.IsLetter(l.currChar) {
+			return l.readNumberToken(currToken)
+		} else if isIdentifierStart(l.currChar) {
 			currToken.Value = l.readIdentifier()
 			currToken.Type = token.LookupIdent(currToken.Value)
+		} else if l.currChar == utf8.RuneError {
+			return currToken, l.error(diag.InvalidEncoding)
 		} else {
-			return currToken, l.error("Unexpected symbol: '%c'", l.currChar)
+			return currToken, l.error(diag.UnexpectedSymbol, l.currChar)
 		}
 	}
 	l.read()
 	return currToken, nil
 }
 
-func (l *Lexer) error(format string, args ...interface{}) error {
-	errorMsg := fmt.Sprintf(format, args...)
-	return errors.New(fmt.Sprintf("%s\nline:%d, pos %d", errorMsg, l.line, l.pos))
+func (l *Lexer) error(code diag.Code, args ...interface{}) error {
+	return l.errorAt(l.line, l.pos, code, args...)
+}
+
+func (l *Lexer) errorAt(line, pos int, code diag.Code, args ...interface{}) error {
+	t := token.Token{Line: line, Col: pos, EndLine: l.line, EndCol: l.pos + 1}
+	return diag.New(diag.PhaseLex, code, t, args...)
+}
+
+// Source returns part of the source code between two positions (as in token.Pos)
+func (l *Lexer) Source(from, to int) string {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(l.input) {
+		to = len(l.input)
+	}
+	if from >= to {
+		return ""
+	}
+	return string(l.input[from:to])
 }
 
 func (l *Lexer) GetCurrLineAndPos() (int, int) {
 	return l.line, l.pos
 }
 
+// skipWhitespace skips spaces and tabs. '\r' is skipped too, so CRLF line endings are treated as '\n'
 func (l *Lexer) skipWhitespace() {
-	for l.currChar == ' ' {
+	for !l.isEOF() && (l.currChar == ' ' || l.currChar == '\t' || l.currChar == '\r') {
 		l.read()
 	}
 }
 
-func (l *Lexer) consumeComment() {
-	for l.currChar != '\n' {
+// consumeComment skips comment till the end of line and returns its text.
+// Leading slashes (both `//` and `///`) and one space after them are trimmed
+func (l *Lexer) consumeComment() string {
+	l.read()
+	var text []rune
+	for !l.isNextEOF() && l.nextChar != '\n' {
+		text = append(text, l.nextChar)
+		l.read()
+	}
+	l.read()
+
+	comment := strings.TrimPrefix(strings.TrimSuffix(string(text), "\r"), "/")
+	return strings.TrimPrefix(comment, " ")
+}
+
+func (l *Lexer) consumeBlockComment() error {
+	line, pos := l.line, l.pos
+	l.read()
+	for {
+		if l.isNextEOF() {
+			return l.errorAt(line, pos, diag.UnterminatedBlockComment)
+		}
 		l.read()
+		if l.currChar == '*' && l.nextChar == '/' {
+			l.read()
+			l.read()
+			return nil
+		}
 	}
 }
 
-func (l *Lexer) readNumber() (string, bool) {
+func (l *Lexer) readNumberToken(currToken token.Token) (token.Token, error) {
+	value, isInt, err := l.readNumber()
+	if err != nil {
+		return currToken, err
+	}
+	currToken.Value = value
+	if isInt {
+		currToken.Type = token.NumInt
+	} else {
+		currToken.Type = token.NumFloat
+	}
+	l.read()
+	return currToken, nil
+}
+
+// readNumber reads decimal, hex (0xFF) or binary (0b1010) number.
+// Decimal number can have fraction part (1.5, 1., .5) and exponent (1e-3, 2.5E6).
+// Digits can be separated by '_' (1_000_000)
+func (l *Lexer) readNumber() (string, bool, error) {
+	if l.currChar == '0' && strings.ContainsRune("xXbB", l.nextChar) {
+		isBaseDigit := isHexDigit
+		if l.nextChar == 'b' || l.nextChar == 'B' {
+			isBaseDigit = isBinaryDigit
+		}
+		l.read()
+		result := "0" + string(l.currChar)
+		digits, err := l.readDigits(isBaseDigit)
+		if err != nil {
+			return "", false, err
+		}
+		if digits == "" {
+			return "", false, l.error(diag.NumberWithoutDigits, result)
+		}
+		if isHexDigit(l.nextChar) || unicode.IsLetter(l.nextChar) {
+			// invalid digit is not read yet, so error points to the next char
+			return "", false, l.errorAt(l.line, l.pos+1, diag.InvalidDigit, l.nextChar)
+		}
+		return result + digits, true, nil
+	}
+
 	isInt := true
 	result := string(l.currChar)
-	for isDigit(l.nextChar) {
-		result += string(l.nextChar)
+	if l.currChar == '.' {
+		isInt = false
+	}
+	digits, err := l.readDigits(isDigit)
+	if err != nil {
+		return "", false, err
+	}
+	result += digits
+
+	if isInt && l.nextChar == '.' {
+		isInt = false
 		l.read()
+		digits, err = l.readDigits(isDigit)
+		if err != nil {
+			return "", false, err
+		}
+		result += "." + digits
 	}
-	if l.nextChar == '.' {
+
+	if l.nextChar == 'e' || l.nextChar == 'E' {
 		isInt = false
 		l.read()
-		result += "."
-		for isDigit(l.nextChar) {
-			result += string(l.nextChar)
+		result += string(l.currChar)
+		if l.nextChar == '+' || l.nextChar == '-' {
 			l.read()
+			result += string(l.currChar)
+		}
+		digits, err = l.readDigits(isDigit)
+		if err != nil {
+			return "", false, err
 		}
+		if digits == "" {
+			return "", false, l.error(diag.ExponentWithoutDigits, result)
+		}
+		result += digits
 	}
 
-	return result, isInt
+	return result, isInt, nil
+}
+
+// readDigits reads digits following current char. '_' is allowed only between digits
+func (l *Lexer) readDigits(isBaseDigit func(rune) bool) (string, error) {
+	var result []rune
+	for isBaseDigit(l.nextChar) || l.nextChar == '_' {
+		if l.nextChar == '_' && (len(result) == 0 && !isBaseDigit(l.currChar) || !isBaseDigit(l.peekChar(2))) {
+			return "", l.error(diag.InvalidDigitSeparator)
+		}
+		result = append(result, l.nextChar)
+		l.read()
+	}
+	return string(result), nil
+}
+
+// peekChar returns char at offset from current position or 0 if it is out of input
+func (l *Lexer) peekChar(offset int) rune {
+	if l.currPosition+offset >= len(l.input) {
+		return rune(0)
+	}
+	return l.input[l.currPosition+offset]
+}
+
+// isEOF reports whether whole input is read. Zero char inside input is not treated as the end
+func (l *Lexer) isEOF() bool {
+	return l.currPosition >= len(l.input)
+}
+
+// isNextEOF reports whether current char is the last one
+func (l *Lexer) isNextEOF() bool {
+	return l.currPosition+1 >= len(l.input)
+}
+
+func (l *Lexer) readString() (string, error) {
+	line, pos := l.line, l.pos
+	var result []rune
+	for l.nextChar != '"' {
+		if l.isNextEOF() {
+			return "", l.errorAt(line, pos, diag.UnterminatedString)
+		}
+		switch l.nextChar {
+		case '\r', '\n':
+			return "", l.errorAt(line, pos, diag.UnterminatedString)
+		case '\\':
+			l.read()
+			switch l.nextChar {
+			case 'n':
+				result = append(result, '\n')
+			case '"', '\\':
+				result = append(result, l.nextChar)
+			default:
+				return "", l.error(diag.UnknownEscapeSequence, l.nextChar)
+			}
+		default:
+			result = append(result, l.nextChar)
+		}
+		l.read()
+	}
+	l.read()
+	return string(result), nil
 }
 
 func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isBinaryDigit(ch rune) bool {
+	return ch == '0' || ch == '1'
+}
+
+func isIdentifierStart(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
 func (l *Lexer) readIdentifier() string {
 	result := string(l.currChar)
-	for unicode.IsLetter(l.nextChar) || isDigit(l.nextChar) {
+	for isIdentifierStart(l.nextChar) || isDigit(l.nextChar) {
 		result += string(l.nextChar)
 		l.read()
 	}
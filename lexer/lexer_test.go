@@ -3,8 +3,11 @@ package lexer
 import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"errors"
 	"testing"
 
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/token"
 )
 
@@ -237,6 +240,59 @@ default:
 	testLexerInput(input, tests, t)
 }
 
+func TestEmptierTokens(t *testing.T) {
+	input := `a = ?int
+b = a ?? 1
+c = p?.x`
+
+	tests := []expectedTestToken{
+		{token.Ident, "a"},
+		{token.Assignment, "="},
+		{token.Question, "?"},
+		{token.Type, "int"},
+		{token.EOL, ""},
+		{token.Ident, "b"},
+		{token.Assignment, "="},
+		{token.Ident, "a"},
+		{token.NullCoalesce, "??"},
+		{token.NumInt, "1"},
+		{token.EOL, ""},
+		{token.Ident, "c"},
+		{token.Assignment, "="},
+		{token.Ident, "p"},
+		{token.SafeDot, "?."},
+		{token.Ident, "x"},
+		{token.EOF, ""},
+	}
+
+	testLexerInput(input, tests, t)
+}
+
+func TestString(t *testing.T) {
+	input := `assert a > 0, "negative \\ \"a\""`
+
+	tests := []expectedTestToken{
+		{token.Assert, "assert"},
+		{token.Ident, "a"},
+		{token.Gt, ">"},
+		{token.NumInt, "0"},
+		{token.Comma, ","},
+		{token.String, `negative \ "a"`},
+		{token.EOF, ""},
+	}
+
+	testLexerInput(input, tests, t)
+}
+
+func TestUnterminatedStringNegative(t *testing.T) {
+	l := New(`a = "abc
+`)
+	_, _ = l.NextToken()
+	_, _ = l.NextToken()
+	_, err := l.NextToken()
+	require.NotNil(t, err)
+}
+
 func TestGetCurrLineAndPos(t *testing.T) {
 	input := `a = 5 + 6
 asd`
@@ -287,3 +343,210 @@ func testLexerInput(input string, tests []expectedTestToken, t *testing.T) {
 		require.Equal(t, tt.expectedValue, tok.Value, "[%d] token value wrong", i)
 	}
 }
+
+func TestBlockComment(t *testing.T) {
+	input := `a = /* inline */ 5
+/* multi
+line */
+b = 1 / 2 // trailing`
+
+	tests := []expectedTestToken{
+		{token.Ident, "a"},
+		{token.Assignment, "="},
+		{token.NumInt, "5"},
+		{token.EOL, ""},
+		{token.EOL, ""},
+		{token.Ident, "b"},
+		{token.Assignment, "="},
+		{token.NumInt, "1"},
+		{token.Slash, "/"},
+		{token.NumInt, "2"},
+		{token.EOF, ""},
+	}
+
+	testLexerInput(input, tests, t)
+}
+
+func TestUnterminatedBlockCommentNegative(t *testing.T) {
+	l := New(`a = 1 /* abc
+`)
+	_, _ = l.NextToken()
+	_, _ = l.NextToken()
+	_, _ = l.NextToken()
+	_, err := l.NextToken()
+	require.NotNil(t, err)
+}
+
+func TestDocComment(t *testing.T) {
+	input := `/// Point on the map
+/// in meters
+struct point {
+   float x // not a doc
+}
+// not a doc because of blank line
+
+// Directions of move
+enum dir {up, down}
+a = 1 // not a doc
+b = 2
+`
+	l := New(input)
+	docs := make(map[string]string)
+	for {
+		tok, err := l.NextToken()
+		require.Nil(t, err)
+		if tok.Type == token.EOF {
+			break
+		}
+		if tok.Doc != "" {
+			docs[tok.Value] = tok.Doc
+		}
+	}
+
+	assert.Equal(t, map[string]string{
+		"struct": "Point on the map\nin meters",
+		"enum":   "Directions of move",
+	}, docs)
+}
+
+func TestNumberLiterals(t *testing.T) {
+	input := `1e-3 2.5E6 0xFF 0b1010 1_000_000 .5 3. 1_0.2_5e+1_0`
+
+	tests := []expectedTestToken{
+		{token.NumFloat, "1e-3"},
+		{token.NumFloat, "2.5E6"},
+		{token.NumInt, "0xFF"},
+		{token.NumInt, "0b1010"},
+		{token.NumInt, "1_000_000"},
+		{token.NumFloat, ".5"},
+		{token.NumFloat, "3."},
+		{token.NumFloat, "1_0.2_5e+1_0"},
+		{token.EOF, ""},
+	}
+
+	testLexerInput(input, tests, t)
+}
+
+func TestNumberLiteralsNegative(t *testing.T) {
+	inputs := []string{"1__0", "1_", "1._5", "0x", "0b102", "0xFG", "1e", "2.5e+", "0x_1"}
+	for _, input := range inputs {
+		l := New(input)
+		_, err := l.NextToken()
+		require.NotNil(t, err, input)
+	}
+
+	// error points to the invalid digit itself
+	for input, col := range map[string]int{"a = 0b102": 9, "0xFG": 4, "0b1z": 4} {
+		l := New(input)
+		var err error
+		for err == nil {
+			var tok token.Token
+			tok, err = l.NextToken()
+			require.NotEqual(t, token.EOF, tok.Type, input)
+		}
+		var diagErr *diag.Error
+		require.True(t, errors.As(err, &diagErr), input)
+		assert.Equal(t, diag.InvalidDigit, diagErr.Code, input)
+		assert.Equal(t, col, diagErr.Col, input)
+	}
+}
+
+func TestEmptyAndShortInput(t *testing.T) {
+	inputs := map[string][]expectedTestToken{
+		"":   {{token.EOF, ""}, {token.EOF, ""}},
+		"a":  {{token.Ident, "a"}, {token.EOF, ""}},
+		"5":  {{token.NumInt, "5"}, {token.EOF, ""}},
+		"\n": {{token.EOL, ""}, {token.EOF, ""}},
+		" ":  {{token.EOF, ""}},
+		"//": {{token.EOF, ""}},
+	}
+	for input, tests := range inputs {
+		testLexerInput(input, tests, t)
+	}
+}
+
+func TestTabsCRLFAndUnderscores(t *testing.T) {
+	input := "if _a1 {\r\n\tmax_speed = 1\r\n}\r\n"
+
+	tests := []expectedTestToken{
+		{token.If, "if"},
+		{token.Ident, "_a1"},
+		{token.LBrace, "{"},
+		{token.EOL, ""},
+		{token.Ident, "max_speed"},
+		{token.Assignment, "="},
+		{token.NumInt, "1"},
+		{token.EOL, ""},
+		{token.RBrace, "}"},
+		{token.EOL, ""},
+		{token.EOF, ""},
+	}
+
+	testLexerInput(input, tests, t)
+}
+
+func TestLineAndPosForMultiByteRunes(t *testing.T) {
+	input := "скорость = 5\n\tы = 1"
+	l := New(input)
+
+	expected := [][2]int{{1, 1}, {1, 10}, {1, 12}, {1, 13}, {2, 2}, {2, 4}, {2, 6}, {2, 7}}
+	for i, pos := range expected {
+		tok, err := l.NextToken()
+		require.Nil(t, err)
+		assert.Equal(t, pos[0], tok.Line, "[%d] line", i)
+		assert.Equal(t, pos[1], tok.Col, "[%d] col", i)
+	}
+}
+
+func TestInvalidInputNegative(t *testing.T) {
+	inputs := []string{"a = \x00", "a = \xff", "a = @"}
+	for _, input := range inputs {
+		l := New(input)
+		_, _ = l.NextToken()
+		_, _ = l.NextToken()
+		_, err := l.NextToken()
+		require.NotNil(t, err, "%q", input)
+		assert.Contains(t, err.Error(), "line:1, pos 5", "%q", input)
+	}
+}
+
+func TestErrorIsStructured(t *testing.T) {
+	l := New(`a = "abc`)
+	_, _ = l.NextToken()
+	_, _ = l.NextToken()
+	_, err := l.NextToken()
+	require.NotNil(t, err)
+
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.PhaseLex, diagErr.Phase)
+	assert.Equal(t, diag.UnterminatedString, diagErr.Code)
+	assert.Equal(t, "Unterminated string literal", diagErr.Message)
+	assert.Equal(t, 1, diagErr.Line)
+	assert.Equal(t, 5, diagErr.Col)
+	assert.Equal(t, "Unterminated string literal\nline:1, pos 5", err.Error())
+}
+
+func TestTokenEndPosition(t *testing.T) {
+	l := New("speed = 10.5\n")
+	tok, _ := l.NextToken()
+	assert.Equal(t, [4]int{1, 1, 1, 6}, [4]int{tok.Line, tok.Col, tok.EndLine, tok.EndCol})
+	_, _ = l.NextToken()
+	tok, _ = l.NextToken()
+	assert.Equal(t, [4]int{1, 9, 1, 13}, [4]int{tok.Line, tok.Col, tok.EndLine, tok.EndCol})
+	tok, _ = l.NextToken()
+	assert.Equal(t, [4]int{1, 13, 1, 14}, [4]int{tok.Line, tok.Col, tok.EndLine, tok.EndCol})
+}
+
+func TestMalformedInputDoesntPanic(t *testing.T) {
+	inputs := []string{"", "\"", "\"\\", "0x", "0b", "1e", "1e+", ".", "1_", "/*", "/", "&", "|", "\xff\xfe", "a\r", "_", "1.e", "0x_1"}
+	for _, input := range inputs {
+		l := New(input)
+		for i := 0; i < len(input)+2; i++ {
+			tok, err := l.NextToken()
+			if err == nil && tok.Type == token.EOF {
+				break
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/interpereter"
 	"github.com/justclimber/marslang/lexer"
 	"github.com/justclimber/marslang/object"
@@ -9,26 +10,33 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 )
 
 func main() {
 	sourceCode, _ := ioutil.ReadFile("example/example1")
 	fmt.Printf("Running source code:\n%s\n", string(sourceCode))
+	renderer := diag.NewRenderer(isTerminal(os.Stderr))
 	l := lexer.New(string(sourceCode))
 	p, err := parser.New(l)
 	if err != nil {
-		log.Fatalf("Lexing error: %s\n", err.Error())
+		log.Fatalf("Lexing error:\n%s\n", renderer.Render(string(sourceCode), err))
 	}
 
 	astProgram, err := p.Parse()
 	if err != nil {
-		log.Fatalf("Parsing error: %s\n", err.Error())
+		log.Fatalf("Parsing error:\n%s\n", renderer.Render(string(sourceCode), err))
 	}
 	env := object.NewEnvironment()
 	fmt.Println("Program output:")
 	err = interpereter.NewExecAstVisitor().ExecAst(astProgram, env)
 	if err != nil {
-		log.Fatalf("Runtime error: %s\n", err.Error())
+		log.Fatalf("Runtime error:\n%s\n", renderer.Render(string(sourceCode), err))
 	}
 	env.Print()
 }
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
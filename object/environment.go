@@ -2,6 +2,7 @@ package object
 
 import (
 	"fmt"
+	"strings"
 )
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
@@ -13,15 +14,22 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 func NewEnvironment() *Environment {
 	return &Environment{
 		store:             make(map[string]Object),
+		consts:            make(map[string]bool),
+		readOnly:          make(map[string]bool),
 		structDefinitions: make(map[string]*StructDefinition),
 		enumDefinitions:   make(map[string]*EnumDefinition),
+		typeDefinitions:   make(map[string]*TypeDefinition),
 	}
 }
 
 type Environment struct {
 	store             map[string]Object
+	order             []string
+	consts            map[string]bool
+	readOnly          map[string]bool
 	structDefinitions map[string]*StructDefinition
 	enumDefinitions   map[string]*EnumDefinition
+	typeDefinitions   map[string]*TypeDefinition
 	outer             *Environment
 }
 
@@ -39,10 +47,82 @@ func (e *Environment) Get(name string) (Object, bool) {
 }
 
 func (e *Environment) Set(name string, val Object) Object {
+	if _, exists := e.store[name]; !exists {
+		e.order = append(e.order, name)
+	}
 	e.store[name] = val
 	return val
 }
 
+// Assign updates variable in the nearest scope where it is defined, so functions can mutate
+// variables of the enclosing scopes. If the variable is not defined anywhere it is created in the current scope
+func (e *Environment) Assign(name string, val Object) Object {
+	if env := e.scopeOf(name); env != nil {
+		return env.Set(name, val)
+	}
+	return e.Set(name, val)
+}
+
+// scopeOf returns the nearest scope where the variable is defined or nil if it is not defined anywhere
+func (e *Environment) scopeOf(name string) *Environment {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env
+		}
+	}
+	return nil
+}
+
+// SetConst sets variable that can't be reassigned by the program. Host still can update its value with Set
+// or SetConst, it stays const
+func (e *Environment) SetConst(name string, val Object) Object {
+	e.Set(name, val)
+	e.consts[name] = true
+	return val
+}
+
+// Consts returns consts of the environment itself, without enclosing scopes
+func (e *Environment) Consts() map[string]Object {
+	consts := make(map[string]Object, len(e.consts))
+	for name := range e.consts {
+		consts[name] = e.store[name]
+	}
+	return consts
+}
+
+// IsConst checks the variable visible from the environment, so const shadowed by the local variable
+// (e.g. function argument) is not const here
+func (e *Environment) IsConst(name string) bool {
+	env := e.scopeOf(name)
+	return env != nil && env.consts[name]
+}
+
+// SetReadOnly sets variable and marks it read-only, see MarkReadOnly
+func (e *Environment) SetReadOnly(name string, val Object) Object {
+	e.Set(name, val)
+	e.MarkReadOnly(name)
+	return val
+}
+
+// MarkReadOnly forbids the program to reassign the variable. If the variable holds struct (or array of structs)
+// all its fields including nested structs become read-only too
+func (e *Environment) MarkReadOnly(name string) bool {
+	val, ok := e.store[name]
+	if !ok {
+		return false
+	}
+	e.readOnly[name] = true
+	markReadOnly(val)
+	return true
+}
+
+// IsReadOnly checks the variable visible from the environment, so read-only variable of the outer scope
+// shadowed by the local one (e.g. function argument) is not read-only here
+func (e *Environment) IsReadOnly(name string) bool {
+	env := e.scopeOf(name)
+	return env != nil && env.readOnly[name]
+}
+
 func (e *Environment) RegisterStructDefinition(s *StructDefinition) error {
 	if _, exists := e.structDefinitions[s.Name]; exists {
 		return fmt.Errorf("struct '%s' already defined in this scope", s.Name)
@@ -61,6 +141,36 @@ func (e *Environment) RegisterEnumDefinition(ed *EnumDefinition) error {
 	return nil
 }
 
+func (e *Environment) RegisterTypeDefinition(td *TypeDefinition) error {
+	if _, exists := e.typeDefinitions[td.Name]; exists {
+		return fmt.Errorf("type '%s' already defined in this scope", td.Name)
+	}
+	e.typeDefinitions[td.Name] = td
+
+	return nil
+}
+
+func (e *Environment) GetTypeDefinition(name string) (*TypeDefinition, bool) {
+	td, ok := e.typeDefinitions[name]
+
+	if !ok && e.outer != nil {
+		td, ok = e.outer.GetTypeDefinition(name)
+	}
+
+	return td, ok
+}
+
+// ResolveType replaces type aliases (including aliases of array elements) with types they refer to
+func (e *Environment) ResolveType(name string) string {
+	if strings.HasPrefix(name, "[]") {
+		return "[]" + e.ResolveType(strings.TrimPrefix(name, "[]"))
+	}
+	if td, ok := e.GetTypeDefinition(name); ok && td.IsAlias {
+		return td.Underlying
+	}
+	return name
+}
+
 func (e *Environment) GetStructDefinition(name string) (*StructDefinition, bool) {
 	s, ok := e.structDefinitions[name]
 
@@ -80,3 +190,15 @@ func (e *Environment) GetEnumDefinition(name string) (*EnumDefinition, bool) {
 
 	return ed, ok
 }
+
+// Names returns names of variables and enums visible from the environment, the nearest scope first
+func (e *Environment) Names() []string {
+	var names []string
+	for env := e; env != nil; env = env.outer {
+		names = append(names, env.order...)
+		for name := range env.enumDefinitions {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -1,30 +1,46 @@
 package object
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 )
 
 func (e *Environment) Print() {
 	fmt.Println("Env content:")
-	for k, v := range e.store {
-		fmt.Printf("%s: %s\n", k, v.Inspect())
+	for _, k := range e.order {
+		fmt.Printf("%s: %s\n", k, e.store[k].Inspect())
 	}
 }
 
+// GetVarsAsJson returns json object with vars in order of their first assignment
 func (e *Environment) GetVarsAsJson() ([]byte, error) {
-	varMap := make(map[string]string)
-	for k, v := range e.store {
-		varMap[k] = v.Inspect()
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, k := range e.order {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(e.store[k].Inspect())
+		if err != nil {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteString(":")
+		out.Write(value)
 	}
-	return json.Marshal(varMap)
+	out.WriteString("}")
+	return out.Bytes(), nil
 }
 
 func (e *Environment) ToStrings() []string {
 	result := make([]string, 0)
-	for k, v := range e.store {
-		result = append(result, fmt.Sprintf("%s: %s\n", k, v.Inspect()))
+	for _, k := range e.order {
+		result = append(result, fmt.Sprintf("%s: %s\n", k, e.store[k].Inspect()))
 	}
 	return result
 }
@@ -38,57 +54,61 @@ func NewEmptyStruct(def *StructDefinition) *Struct {
 }
 
 func (e *Environment) Keys() []string {
-	keys := make([]string, len(e.store))
-
-	i := 0
-	for k := range e.store {
-		keys[i] = k
-		i++
-	}
+	keys := make([]string, len(e.order))
+	copy(keys, e.order)
 	return keys
 }
 
-func (e *Environment) LoadVarsInStruct(definition *StructDefinition, s map[string]interface{}) *Struct {
+// LoadVarsInStruct creates struct from host values. Supported values are float64, float32, int, int32, int64,
+// uint32, bool and language objects
+func (e *Environment) LoadVarsInStruct(definition *StructDefinition, s map[string]interface{}) (*Struct, error) {
 	fields := make(map[string]Object)
 	for k, v := range s {
-		fields[k] = getLangObject(v)
+		obj, err := getLangObject(v)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s' of struct '%s': %w", k, definition.Name, err)
+		}
+		fields[k] = obj
 	}
 	return &Struct{
 		Definition: definition,
 		Fields:     fields,
-	}
+	}, nil
 }
 
-func getLangType(t interface{}) string {
-	switch t.(type) {
-	case float64:
-		return TypeFloat
-	case int:
-		return TypeInt
-	case int32:
-		return TypeInt
-	case bool:
-		return TypeBool
-	default:
-		log.Fatalf("Unsupported type for struct creation: '%T'", t)
+func markReadOnly(obj Object) {
+	switch o := obj.(type) {
+	case *Struct:
+		o.ReadOnly = true
+		for _, field := range o.Fields {
+			markReadOnly(field)
+		}
+	case *Array:
+		for _, el := range o.Elements {
+			markReadOnly(el)
+		}
 	}
-	return ""
 }
 
-func getLangObject(t interface{}) Object {
+func getLangObject(t interface{}) (Object, error) {
 	switch tt := t.(type) {
 	case float64:
-		return &Float{Value: tt}
+		return &Float{Value: tt}, nil
+	case float32:
+		return &Float{Value: float64(tt)}, nil
 	case int:
-		return &Integer{Value: int64(tt)}
+		return &Integer{Value: int64(tt)}, nil
 	case int32:
-		return &Integer{Value: int64(tt)}
+		return &Integer{Value: int64(tt)}, nil
+	case int64:
+		return &Integer{Value: tt}, nil
 	case uint32:
-		return &Integer{Value: int64(tt)}
+		return &Integer{Value: int64(tt)}, nil
 	case bool:
-		return &Boolean{Value: tt}
+		return &Boolean{Value: tt}, nil
+	case Object:
+		return tt, nil
 	default:
-		log.Fatalf("Unsupported type for struct creation: '%T'", t)
+		return nil, fmt.Errorf("unsupported type for struct creation: '%T'", t)
 	}
-	return nil
 }
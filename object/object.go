@@ -4,7 +4,9 @@ import (
 	"github.com/justclimber/marslang/ast"
 
 	"bytes"
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -18,6 +20,7 @@ const (
 	TypeFunction    = "function_obj"
 	TypeBuiltinFn   = "builtin_fn_obj"
 	TypeVoid        = "void"
+	TypeError       = "error"
 )
 
 type Object interface {
@@ -31,9 +34,59 @@ type Emptier struct {
 
 func (e *Emptier) IsEmpty() bool { return e.Empty }
 
+// Emptiable is implemented by objects that can hold typed empty value
+type Emptiable interface {
+	IsEmpty() bool
+}
+
+// TypeDefinition is user-defined type: distinct type based on int or float (`type Angle float`)
+// that requires explicit conversion, or transparent alias for any other type (`type Speed = float`)
+type TypeDefinition struct {
+	Name       string
+	Underlying string
+	IsAlias    bool
+}
+
+// Named is embedded into objects that can have distinct user-defined type
+type Named struct {
+	TypeDefinition *TypeDefinition
+}
+
+func (n *Named) typeOr(t ObjectType) ObjectType {
+	if n.TypeDefinition != nil {
+		return ObjectType(n.TypeDefinition.Name)
+	}
+	return t
+}
+
 type StructDefinition struct {
-	Name   string
-	Fields map[string]string
+	Name        string
+	Fields      map[string]string
+	FieldsOrder []string
+}
+
+// FieldNames returns field names in the declaration order. Fields that are missing in FieldsOrder
+// (e.g. definition was created by host without order) goes after declared ones sorted by name
+func (sd *StructDefinition) FieldNames() []string {
+	if len(sd.FieldsOrder) == len(sd.Fields) {
+		return sd.FieldsOrder
+	}
+	names := make([]string, 0, len(sd.Fields))
+	ordered := make(map[string]bool)
+	for _, name := range sd.FieldsOrder {
+		if _, ok := sd.Fields[name]; ok {
+			names = append(names, name)
+			ordered[name] = true
+		}
+	}
+	rest := make([]string, 0)
+	for name := range sd.Fields {
+		if !ordered[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
 }
 
 type EnumDefinition struct {
@@ -41,12 +94,17 @@ type EnumDefinition struct {
 	Elements []string
 }
 
-func CreateVarDefinitionsFromVarType(varTypes map[string]*ast.VarAndType) map[string]string {
-	varDefinitions := make(map[string]string)
-	for k, v := range varTypes {
-		varDefinitions[k] = v.VarType
+func NewStructDefinition(name string, varTypes []*ast.VarAndType) *StructDefinition {
+	sd := &StructDefinition{
+		Name:        name,
+		Fields:      make(map[string]string),
+		FieldsOrder: make([]string, 0, len(varTypes)),
+	}
+	for _, v := range varTypes {
+		sd.Fields[v.Var.Value] = v.VarType
+		sd.FieldsOrder = append(sd.FieldsOrder, v.Var.Value)
 	}
-	return varDefinitions
+	return sd
 }
 
 type IIdentifier interface{}
@@ -54,35 +112,50 @@ type IStatements interface{}
 
 type Integer struct {
 	Emptier
+	Named
 	Value int64
 }
 
-func (i *Integer) Type() ObjectType { return TypeInt }
+func (i *Integer) Type() ObjectType { return i.typeOr(TypeInt) }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
 type Float struct {
 	Emptier
+	Named
 	Value float64
 }
 
-func (f *Float) Type() ObjectType { return TypeFloat }
+func (f *Float) Type() ObjectType { return f.typeOr(TypeFloat) }
 func (f *Float) Inspect() string  { return fmt.Sprintf("%.2f", f.Value) }
 
 type Boolean struct {
+	Emptier
 	Value bool
 }
 
 func (b *Boolean) Type() ObjectType { return TypeBool }
-func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
+func (b *Boolean) Inspect() string {
+	if b.Empty {
+		return "?" + TypeBool
+	}
+	return fmt.Sprintf("%t", b.Value)
+}
 
 type Enum struct {
+	Emptier
 	Definition *EnumDefinition
 	Value      int8
 }
 
 func (e *Enum) Type() ObjectType { return ObjectType(e.Definition.Name) }
 func (e *Enum) Inspect() string {
-	return fmt.Sprintf("%s", e.Definition.Elements[e.Value])
+	if e.Empty {
+		return "?" + e.Definition.Name
+	}
+	if e.Value < 0 || int(e.Value) >= len(e.Definition.Elements) {
+		return fmt.Sprintf("%s(%d)", e.Definition.Name, e.Value)
+	}
+	return e.Definition.Elements[e.Value]
 }
 
 type Array struct {
@@ -112,6 +185,8 @@ func (rv *ReturnValue) Type() ObjectType { return TypeReturnValue }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Function struct {
+	// name of the variable function was bound to first, empty for anonymous functions
+	Name       string
 	Arguments  []*ast.VarAndType
 	Statements *ast.StatementsBlock
 	ReturnType string
@@ -125,8 +200,22 @@ func (f *Function) Inspect() string {
 
 type Struct struct {
 	Emptier
-	Definition *StructDefinition
-	Fields     map[string]Object
+	Definition     *StructDefinition
+	Fields         map[string]Object
+	ReadOnly       bool
+	readOnlyFields map[string]bool
+}
+
+// MarkFieldReadOnly forbids the program to assign the field of this struct
+func (s *Struct) MarkFieldReadOnly(field string) {
+	if s.readOnlyFields == nil {
+		s.readOnlyFields = make(map[string]bool)
+	}
+	s.readOnlyFields[field] = true
+}
+
+func (s *Struct) IsFieldReadOnly(field string) bool {
+	return s.ReadOnly || s.readOnlyFields[field]
 }
 
 func (s *Struct) Type() ObjectType { return ObjectType(s.Definition.Name) }
@@ -134,8 +223,10 @@ func (s *Struct) Inspect() string {
 	var out bytes.Buffer
 
 	var elements []string
-	for k, v := range s.Fields {
-		elements = append(elements, fmt.Sprintf("%s: %s", k, v.Inspect()))
+	for _, k := range s.Definition.FieldNames() {
+		if v, ok := s.Fields[k]; ok {
+			elements = append(elements, fmt.Sprintf("%s: %s", k, v.Inspect()))
+		}
 	}
 
 	out.WriteString(s.Definition.Name)
@@ -146,7 +237,9 @@ func (s *Struct) Inspect() string {
 	return out.String()
 }
 
-type BuiltinFunction func(env *Environment, args []Object) (Object, error)
+// BuiltinFunction is a host function callable from the program. ctx is the context of the execution,
+// long running builtins should stop when it is done
+type BuiltinFunction func(ctx context.Context, env *Environment, args []Object) (Object, error)
 
 type ArgTypes []string
 
@@ -160,6 +253,14 @@ type Builtin struct {
 func (b *Builtin) Type() ObjectType { return TypeBuiltinFn }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+// Error is recoverable runtime error caught by try/recover block
+type Error struct {
+	Message string
+}
+
+func (e *Error) Type() ObjectType { return TypeError }
+func (e *Error) Inspect() string  { return e.Message }
+
 type Void struct{}
 
 func (v *Void) Type() ObjectType { return TypeVoid }
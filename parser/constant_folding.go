@@ -0,0 +1,203 @@
+package parser
+
+import (
+	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
+	"github.com/justclimber/marslang/object"
+	"github.com/justclimber/marslang/token"
+
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// foldConstExpression evaluates expression of const definition at parse time.
+// Only literals, previously defined consts and unary/binary operations on them are allowed
+func (p *Parser) foldConstExpression(expr ast.IExpression) (ast.IExpression, error) {
+	switch node := expr.(type) {
+	case *ast.NumInt, *ast.NumFloat, *ast.Boolean:
+		return node, nil
+	case *ast.Identifier:
+		value, ok := p.consts[node.Value]
+		if !ok {
+			return nil, p.parseErrorAt(node.Token, diag.NotConst, node.Value)
+		}
+		return value, nil
+	case *ast.UnaryExpression:
+		right, err := p.foldConstExpression(node.Right)
+		if err != nil {
+			return nil, err
+		}
+		return p.foldConstUnaryExpression(node, right)
+	case *ast.BinExpression:
+		left, err := p.foldConstExpression(node.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.foldConstExpression(node.Right)
+		if err != nil {
+			return nil, err
+		}
+		return p.foldConstBinExpression(node, left, right)
+	default:
+		return nil, p.parseErrorAt(expr.GetToken(), diag.NotConstExpression, expr)
+	}
+}
+
+func (p *Parser) foldConstUnaryExpression(node *ast.UnaryExpression, right ast.IExpression) (ast.IExpression, error) {
+	switch r := right.(type) {
+	case *ast.NumInt:
+		if node.Operator == token.Minus {
+			if r.Value == math.MinInt64 {
+				return nil, p.parseErrorAt(node.Token, diag.IntOverflow, fmt.Sprintf("-(%d)", r.Value))
+			}
+			return newFoldedInt(node.Token, -r.Value), nil
+		}
+	case *ast.NumFloat:
+		if node.Operator == token.Minus {
+			return newFoldedFloat(node.Token, -r.Value), nil
+		}
+	case *ast.Boolean:
+		if node.Operator == token.Not {
+			return newFoldedBoolean(node.Token, !r.Value), nil
+		}
+	}
+	return nil, p.parseErrorAt(node.Token, diag.ConstUnknownOperator, node.Operator, right)
+}
+
+func (p *Parser) foldConstBinExpression(node *ast.BinExpression, left, right ast.IExpression) (ast.IExpression, error) {
+	switch l := left.(type) {
+	case *ast.NumInt:
+		if r, ok := right.(*ast.NumInt); ok {
+			return p.foldConstIntBinExpression(node, l.Value, r.Value)
+		}
+	case *ast.NumFloat:
+		if r, ok := right.(*ast.NumFloat); ok {
+			return p.foldConstFloatBinExpression(node, l.Value, r.Value)
+		}
+	case *ast.Boolean:
+		if r, ok := right.(*ast.Boolean); ok {
+			return p.foldConstBooleanBinExpression(node, l.Value, r.Value)
+		}
+	}
+	return nil, p.parseErrorAt(node.Token, diag.ConstOperandTypesMismatch, left, right)
+}
+
+func (p *Parser) foldConstIntBinExpression(node *ast.BinExpression, left, right int64) (ast.IExpression, error) {
+	var result int64
+	var overflow bool
+	switch node.Operator {
+	case token.Plus:
+		result = left + right
+		overflow = (left^result)&(right^result) < 0
+	case token.Minus:
+		result = left - right
+		overflow = (left^right)&(left^result) < 0
+	case token.Asterisk:
+		result = left * right
+		overflow = left != 0 && (result/left != right || left == -1 && right == math.MinInt64)
+	case token.Slash:
+		if right == 0 {
+			return nil, p.parseErrorAt(node.Token, diag.ConstDivisionByZero)
+		}
+		result = left / right
+		overflow = left == math.MinInt64 && right == -1
+	case token.Lt:
+		return newFoldedBoolean(node.Token, left < right), nil
+	case token.Gt:
+		return newFoldedBoolean(node.Token, left > right), nil
+	case token.Eq:
+		return newFoldedBoolean(node.Token, left == right), nil
+	case token.NotEq:
+		return newFoldedBoolean(node.Token, left != right), nil
+	default:
+		return nil, p.parseErrorAt(node.Token, diag.ConstUnsupportedOperator, node.Operator, "int")
+	}
+	// const value is computed once for all executions, so overflow is an error regardless of numeric policy
+	if overflow {
+		return nil, p.parseErrorAt(node.Token, diag.IntOverflow, fmt.Sprintf("%d %s %d", left, node.Operator, right))
+	}
+	return newFoldedInt(node.Token, result), nil
+}
+
+func (p *Parser) foldConstFloatBinExpression(node *ast.BinExpression, left, right float64) (ast.IExpression, error) {
+	switch node.Operator {
+	case token.Plus:
+		return newFoldedFloat(node.Token, left+right), nil
+	case token.Minus:
+		return newFoldedFloat(node.Token, left-right), nil
+	case token.Asterisk:
+		return newFoldedFloat(node.Token, left*right), nil
+	case token.Slash:
+		if right == 0 {
+			return nil, p.parseErrorAt(node.Token, diag.ConstDivisionByZero)
+		}
+		return newFoldedFloat(node.Token, left/right), nil
+	case token.Lt:
+		return newFoldedBoolean(node.Token, left < right), nil
+	case token.Gt:
+		return newFoldedBoolean(node.Token, left > right), nil
+	case token.Eq:
+		return newFoldedBoolean(node.Token, left == right), nil
+	case token.NotEq:
+		return newFoldedBoolean(node.Token, left != right), nil
+	default:
+		return nil, p.parseErrorAt(node.Token, diag.ConstUnsupportedOperator, node.Operator, "float")
+	}
+}
+
+func (p *Parser) foldConstBooleanBinExpression(node *ast.BinExpression, left, right bool) (ast.IExpression, error) {
+	switch node.Operator {
+	case token.Eq:
+		return newFoldedBoolean(node.Token, left == right), nil
+	case token.NotEq:
+		return newFoldedBoolean(node.Token, left != right), nil
+	case token.And:
+		return newFoldedBoolean(node.Token, left && right), nil
+	case token.Or:
+		return newFoldedBoolean(node.Token, left || right), nil
+	default:
+		return nil, p.parseErrorAt(node.Token, diag.ConstUnsupportedOperator, node.Operator, "bool")
+	}
+}
+
+// foldedHostConst converts const value set by the host to the folded node. Node has no position in the source
+func foldedHostConst(value object.Object) (ast.IExpression, bool) {
+	switch v := value.(type) {
+	case *object.Integer:
+		if !v.Empty {
+			return newFoldedInt(token.Token{}, v.Value), true
+		}
+	case *object.Float:
+		if !v.Empty {
+			return newFoldedFloat(token.Token{}, v.Value), true
+		}
+	case *object.Boolean:
+		if !v.Empty {
+			return newFoldedBoolean(token.Token{}, v.Value), true
+		}
+	}
+	return nil, false
+}
+
+func newFoldedInt(t token.Token, value int64) *ast.NumInt {
+	t.Type = token.NumInt
+	t.Value = strconv.FormatInt(value, 10)
+	return &ast.NumInt{Token: t, Value: value}
+}
+
+func newFoldedFloat(t token.Token, value float64) *ast.NumFloat {
+	t.Type = token.NumFloat
+	t.Value = strconv.FormatFloat(value, 'f', -1, 64)
+	return &ast.NumFloat{Token: t, Value: value}
+}
+
+func newFoldedBoolean(t token.Token, value bool) *ast.Boolean {
+	if value {
+		t.Type = token.True
+	} else {
+		t.Type = token.False
+	}
+	t.Value = strconv.FormatBool(value)
+	return &ast.Boolean{Token: t, Value: value}
+}
@@ -2,18 +2,28 @@ package parser
 
 import (
 	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/lexer"
+	"github.com/justclimber/marslang/object"
 	"github.com/justclimber/marslang/token"
 
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
+// DefaultMaxErrors is a number of errors after which parser stops if not set by SetMaxErrors
+const DefaultMaxErrors = 10
+
+// MaxNestingDepth limits nesting of expressions and blocks, so parsing of malicious code doesn't exhaust the stack
+const MaxNestingDepth = 500
+
 const (
 	_ int = iota
 	Lowest
 	Assignment // =
+	Coalesce   // ??
 	Or         // ||
 	And        // &&
 	Equals     // ==
@@ -26,24 +36,29 @@ const (
 )
 
 var precedences = map[token.TokenType]int{
-	token.Eq:         Equals,
-	token.NotEq:      Equals,
-	token.Lt:         Comparison,
-	token.Gt:         Comparison,
-	token.Assignment: Assignment,
-	token.And:        And,
-	token.Or:         Or,
-	token.Plus:       Sum,
-	token.Minus:      Sum,
-	token.Slash:      Product,
-	token.Asterisk:   Product,
-	token.LParen:     Call,
-	token.LBracket:   Index,
-	token.LBrace:     Index,
-	token.Dot:        Index,
-	token.Colon:      Index,
+	token.Eq:           Equals,
+	token.NotEq:        Equals,
+	token.Lt:           Comparison,
+	token.Gt:           Comparison,
+	token.Assignment:   Assignment,
+	token.NullCoalesce: Coalesce,
+	token.SafeDot:      Index,
+	token.And:          And,
+	token.Or:           Or,
+	token.Plus:         Sum,
+	token.Minus:        Sum,
+	token.Slash:        Product,
+	token.Asterisk:     Product,
+	token.LParen:       Call,
+	token.LBracket:     Index,
+	token.LBrace:       Index,
+	token.Dot:          Index,
+	token.Colon:        Index,
 }
 
+// typeDefinitionKeyword is contextual keyword, so "type" remains valid identifier (e.g. for struct fields)
+const typeDefinitionKeyword = "type"
+
 type (
 	unaryExprFunction func([]token.TokenType) (ast.IExpression, error)
 	binExprFunctions  func(ast.IExpression, []token.TokenType) (ast.IExpression, error)
@@ -58,20 +73,34 @@ type Parser struct {
 
 	unaryExprFunctions map[token.TokenType]unaryExprFunction
 	binExprFunctions   map[token.TokenType]binExprFunctions
+
+	// folded values of consts defined so far
+	consts     map[string]ast.IExpression
+	blockDepth int
+	exprDepth  int
+	errors     diag.List
+	maxErrors  int
+	locale     diag.Locale
+
+	// depth of open brackets inside expression. Line breaks are insignificant while it is positive
+	nestingLevel int
 }
 
 func New(l *lexer.Lexer) (*Parser, error) {
-	p := &Parser{l: l}
+	p := &Parser{
+		l:         l,
+		consts:    make(map[string]ast.IExpression),
+		maxErrors: DefaultMaxErrors,
+		locale:    diag.DefaultLocale,
+	}
 
+	// lexer errors are collected like all others and returned by Parse
 	var err error
-	p.currToken, err = p.l.NextToken()
-	if err != nil {
-		return nil, err
+	if p.currToken, err = p.fetchToken(token.Token{}); err != nil {
+		p.addError(err)
 	}
-
-	p.nextToken, err = p.l.NextToken()
-	if err != nil {
-		return nil, err
+	if p.nextToken, err = p.fetchToken(p.currToken); err != nil {
+		p.addError(err)
 	}
 
 	p.unaryExprFunctions = make(map[token.TokenType]unaryExprFunction)
@@ -82,10 +111,12 @@ func New(l *lexer.Lexer) (*Parser, error) {
 	p.registerUnaryExprFunction(token.True, p.parseBoolean)
 	p.registerUnaryExprFunction(token.False, p.parseBoolean)
 	p.registerUnaryExprFunction(token.Ident, p.parseIdentifierAsExpression)
+	p.registerUnaryExprFunction(token.Type, p.parseTypeAsIdentifier)
 	p.registerUnaryExprFunction(token.LParen, p.parseGroupedExpression)
 	p.registerUnaryExprFunction(token.Function, p.parseFunction)
 	p.registerUnaryExprFunction(token.LBracket, p.parseArray)
 	p.registerUnaryExprFunction(token.Question, p.parseEmptierExpression)
+	p.registerUnaryExprFunction(token.If, p.parseIfExpression)
 
 	p.binExprFunctions = make(map[token.TokenType]binExprFunctions)
 	p.registerBinExprFunction(token.Plus, p.parseBinExpression)
@@ -96,12 +127,14 @@ func New(l *lexer.Lexer) (*Parser, error) {
 	p.registerBinExprFunction(token.Eq, p.parseBinExpression)
 	p.registerBinExprFunction(token.And, p.parseBinExpression)
 	p.registerBinExprFunction(token.Or, p.parseBinExpression)
+	p.registerBinExprFunction(token.NullCoalesce, p.parseBinExpression)
 	p.registerBinExprFunction(token.NotEq, p.parseBinExpression)
 	p.registerBinExprFunction(token.Asterisk, p.parseBinExpression)
 	p.registerBinExprFunction(token.LParen, p.parseFunctionCall)
 	p.registerBinExprFunction(token.LBracket, p.parseArrayIndexCall)
 	p.registerBinExprFunction(token.LBrace, p.parseStructExpression)
 	p.registerBinExprFunction(token.Dot, p.parseStructFieldCall)
+	p.registerBinExprFunction(token.SafeDot, p.parseStructFieldCall)
 	p.registerBinExprFunction(token.Colon, p.parseEnumExpression)
 
 	return p, nil
@@ -111,10 +144,37 @@ func (p *Parser) read() error {
 	var err error
 	p.prevToken = p.currToken
 	p.currToken = p.nextToken
-	p.nextToken, err = p.l.NextToken()
+	p.nextToken, err = p.fetchToken(p.currToken)
 	if err != nil {
 		return err
 	}
+	if p.nestingLevel > 0 && p.currToken.Type == token.EOL {
+		return p.read()
+	}
+	return nil
+}
+
+// fetchToken returns next token from lexer. If source doesn't end with line break,
+// EOL is inserted before EOF, so the last statement is terminated as usual
+func (p *Parser) fetchToken(prev token.Token) (token.Token, error) {
+	t, err := p.l.NextToken()
+	if err != nil {
+		return t, err
+	}
+	if t.Type == token.EOF && prev.Type != "" && prev.Type != token.EOL && prev.Type != token.EOF {
+		t.Type = token.EOL
+	}
+	return t, nil
+}
+
+// skipNextEols drops line breaks following current token inside brackets
+func (p *Parser) skipNextEols() error {
+	var err error
+	for p.nestingLevel > 0 && p.nextToken.Type == token.EOL {
+		if p.nextToken, err = p.fetchToken(p.nextToken); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -138,37 +198,132 @@ func (p *Parser) back() {
 	_ = p.read()
 }
 
-func (p *Parser) Parse() (*ast.StatementsBlock, error) {
-	program := &ast.StatementsBlock{}
+// SetMaxErrors limits number of errors parser collects before it stops. 0 or less means no limit
+func (p *Parser) SetMaxErrors(maxErrors int) {
+	p.maxErrors = maxErrors
+}
+
+// SetLocale sets language of error messages returned by Parse
+func (p *Parser) SetLocale(locale diag.Locale) {
+	p.locale = locale
+}
+
+// SetConsts makes host consts (e.g. env.Consts()) available for const expressions of the program.
+// Values are taken at parse time, only non-empty int, float and bool consts can be folded, others are ignored
+func (p *Parser) SetConsts(consts map[string]object.Object) {
+	for name, value := range consts {
+		if folded, ok := foldedHostConst(value); ok {
+			p.consts[name] = folded
+		}
+	}
+}
+
+// Parse parses the whole program. Parsing continues after errors from the next statement,
+// so all found errors are returned as diag.List together with AST of successfully parsed statements.
+// Panic during parsing is returned as diag.Internal error pointing to the current token
+func (p *Parser) Parse() (program *ast.StatementsBlock, err error) {
+	program = &ast.StatementsBlock{}
+	defer func() {
+		if r := recover(); r != nil {
+			p.addError(diag.New(diag.PhaseParse, diag.Internal, p.currToken, fmt.Sprint(r)))
+			diag.Localize(p.errors, p.locale)
+			err = p.errors
+		}
+	}()
 
 	statements, err := p.parseBlockOfStatements(token.GetTokenTypes(token.EOF))
+	if err != nil {
+		p.addError(err)
+	}
 	program.Statements = statements
 
-	return program, err
+	if len(p.errors) > 0 {
+		diag.Localize(p.errors, p.locale)
+		return program, p.errors
+	}
+	return program, nil
 }
 
 func (p *Parser) parseBlockOfStatements(terminatedTokens []token.TokenType) ([]ast.IStatement, error) {
 	var statements []ast.IStatement
+	p.blockDepth++
+	defer func() { p.blockDepth-- }()
+	if p.blockDepth+p.exprDepth > MaxNestingDepth {
+		return nil, p.parseError(diag.NestingTooDeep, MaxNestingDepth)
+	}
 
-	for !p.currTokenIn(terminatedTokens) {
+	for !p.currTokenIn(terminatedTokens) && !p.isStopped() {
+		// unterminated block: nothing to skip till the closing brace, so the error is reported once
+		if p.currToken.Type == token.EOF {
+			return statements, p.parseError(diag.UnexpectedToken, token.RBrace, token.EOF)
+		}
+		// error of illegal token is already reported by lexer
+		if p.currToken.Type == token.Illegal {
+			if err := p.read(); err != nil {
+				p.addError(err)
+			}
+			continue
+		}
 		stmt, err := p.parseStatement()
 		if err != nil {
-			return nil, err
-		}
-		if stmt != nil {
+			p.addError(err)
+			p.synchronize(terminatedTokens)
+			if p.currToken.Type != token.EOL {
+				continue
+			}
+		} else if stmt != nil {
 			statements = append(statements, stmt)
 		}
 		if err = p.read(); err != nil {
-			return nil, err
+			p.addError(err)
 		}
 	}
 	return statements, nil
 }
 
+// synchronize skips tokens after error till the end of the statement or the end of the current block.
+// Blocks opened in the skipped part are skipped entirely
+func (p *Parser) synchronize(terminatedTokens []token.TokenType) {
+	depth := 0
+	for p.currToken.Type != token.EOF {
+		if depth == 0 && (p.currToken.Type == token.EOL || p.currTokenIn(terminatedTokens)) {
+			return
+		}
+		switch p.currToken.Type {
+		case token.LBrace:
+			depth++
+		case token.RBrace:
+			if depth > 0 {
+				depth--
+			}
+		}
+		if err := p.read(); err != nil {
+			p.addError(err)
+		}
+	}
+}
+
+func (p *Parser) addError(err error) {
+	if p.isStopped() {
+		return
+	}
+	var diagErr *diag.Error
+	if !errors.As(err, &diagErr) {
+		diagErr = diag.New(diag.PhaseParse, diag.Internal, p.currToken, err.Error())
+	}
+	p.errors = append(p.errors, diagErr)
+}
+
+func (p *Parser) isStopped() bool {
+	return p.maxErrors > 0 && len(p.errors) >= p.maxErrors
+}
+
 func (p *Parser) parseStatement() (ast.IStatement, error) {
 	switch p.currToken.Type {
 	case token.Ident:
-		if p.nextToken.Type == token.LParen {
+		if p.currToken.Value == typeDefinitionKeyword && p.nextToken.Type == token.Ident {
+			return p.parseTypeDefinition()
+		} else if p.nextToken.Type == token.LParen {
 			function := &ast.Identifier{
 				Token: p.currToken,
 				Value: p.currToken.Value,
@@ -181,8 +336,14 @@ func (p *Parser) parseStatement() (ast.IStatement, error) {
 		} else if p.nextToken.Type == token.Dot {
 			return p.parseStructFieldAssignment(token.GetTokenTypes(token.EOL))
 		} else {
+			// const may be shadowed by function argument, so nested assignments are checked at runtime
+			if _, isConst := p.consts[p.currToken.Value]; isConst && p.blockDepth == 1 {
+				return nil, p.parseError(diag.AssignmentToConst, p.currToken.Value)
+			}
 			return p.parseAssignment(token.GetTokenTypes(token.EOL))
 		}
+	case token.Const:
+		return p.parseConstDefinition()
 	case token.Return:
 		return p.parseReturn()
 	case token.If:
@@ -193,10 +354,14 @@ func (p *Parser) parseStatement() (ast.IStatement, error) {
 		return p.parseEnumDefinition()
 	case token.Switch:
 		return p.parseSwitchStatement()
+	case token.Try:
+		return p.parseTryStatement()
+	case token.Assert:
+		return p.parseAssertStatement()
 	case token.EOL:
 		return nil, nil
 	default:
-		return nil, p.parseError("Unexpected token for start of statement: %s\n", p.currToken.Type)
+		return nil, p.parseError(diag.UnexpectedStatementStart, p.currToken.Type)
 	}
 }
 
@@ -276,6 +441,10 @@ func (p *Parser) parseAssignment(terminatedTokens []token.TokenType) (*ast.Assig
 	if err != nil {
 		return nil, err
 	}
+	// functions are always defined as variables, so doc comment is placed above the variable
+	if function, ok := assignStmt.Value.(*ast.Function); ok {
+		function.Doc = assignStmt.Token.Doc
+	}
 	if err = p.read(); err != nil {
 		return nil, err
 	}
@@ -287,6 +456,38 @@ func (p *Parser) parseAssignment(terminatedTokens []token.TokenType) (*ast.Assig
 	return assignStmt, nil
 }
 
+func (p *Parser) parseConstDefinition() (*ast.ConstDefinition, error) {
+	node := &ast.ConstDefinition{Token: p.currToken}
+	if p.blockDepth > 1 {
+		return nil, p.parseError(diag.ConstNotAtTopLevel)
+	}
+
+	if err := p.read(); err != nil {
+		return nil, err
+	}
+	name, err := p.getExpectedToken(token.Ident)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := p.consts[name.Value]; exists {
+		return nil, p.parseError(diag.ConstRedefinition, name.Value)
+	}
+
+	assignment, err := p.parseAssignment(token.GetTokenTypes(token.EOL))
+	if err != nil {
+		return nil, err
+	}
+	node.Left = assignment.Left
+
+	node.Value, err = p.foldConstExpression(assignment.Value)
+	if err != nil {
+		return nil, err
+	}
+	p.consts[node.Left.Value] = node.Value
+
+	return node, nil
+}
+
 func (p *Parser) parseReturn() (*ast.Return, error) {
 	stmt := &ast.Return{Token: p.currToken}
 	var err error
@@ -304,9 +505,15 @@ func (p *Parser) parseReturn() (*ast.Return, error) {
 }
 
 func (p *Parser) parseExpression(precedence int, terminatedTokens []token.TokenType) (ast.IExpression, error) {
+	p.exprDepth++
+	defer func() { p.exprDepth-- }()
+	if p.blockDepth+p.exprDepth > MaxNestingDepth {
+		return nil, p.parseError(diag.NestingTooDeep, MaxNestingDepth)
+	}
+
 	unaryFunction := p.unaryExprFunctions[p.currToken.Type]
 	if unaryFunction == nil {
-		err := p.parseError("no Unary parse function for %s found", p.currToken.Type)
+		err := p.parseError(diag.UnexpectedExpressionStart, p.currToken.Type)
 		return nil, err
 	}
 
@@ -323,12 +530,16 @@ func (p *Parser) parseRightPartOfExpression(
 	precedence int,
 	terminatedTokens []token.TokenType,
 ) (ast.IExpression, error) {
+	if err := p.skipNextEols(); err != nil {
+		return nil, err
+	}
 	var err error
-	nextPrecedence := p.nextPrecedence()
-	for !p.nextTokenIn(terminatedTokens) && precedence < nextPrecedence {
+	// precedence of the next operator is checked on every iteration, so operators of the lower precedence
+	// are left to the caller and chains like "1 - 2 * 3 - 4" are left-associative
+	for !p.nextTokenIn(terminatedTokens) && precedence < p.nextPrecedence() {
 		binExprFunction := p.binExprFunctions[p.nextToken.Type]
 		if binExprFunction == nil {
-			err := p.parseError("Unexpected next token for binary expression '%s'", p.nextToken.Type)
+			err := p.parseError(diag.UnexpectedBinaryOperator, p.nextToken.Type)
 			return nil, err
 		}
 
@@ -340,6 +551,9 @@ func (p *Parser) parseRightPartOfExpression(
 		if err != nil {
 			return nil, err
 		}
+		if err = p.skipNextEols(); err != nil {
+			return nil, err
+		}
 	}
 	return leftExpr, nil
 }
@@ -355,6 +569,14 @@ func (p *Parser) parseIdentifierAsExpression(terminatedTokens []token.TokenType)
 	}, nil
 }
 
+// parseTypeAsIdentifier parses type name in expression context, e.g. for type conversion `float(a)`
+func (p *Parser) parseTypeAsIdentifier(terminatedTokens []token.TokenType) (ast.IExpression, error) {
+	return &ast.Identifier{
+		Token: p.currToken,
+		Value: p.currToken.Value,
+	}, nil
+}
+
 func (p *Parser) parseIdentifier(terminatedTokens []token.TokenType) (*ast.Identifier, error) {
 	expr, err := p.parseIdentifierAsExpression(terminatedTokens)
 	if err != nil {
@@ -368,8 +590,11 @@ func (p *Parser) parseInteger(terminatedTokens []token.TokenType) (ast.IExpressi
 	node := &ast.NumInt{Token: p.currToken}
 
 	value, err := strconv.ParseInt(p.currToken.Value, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, p.parseError(diag.IntLiteralOverflow, p.currToken.Value)
+	}
 	if err != nil {
-		err := p.parseError("could not parse %q as integer", p.currToken.Value)
+		err := p.parseError(diag.InvalidIntLiteral, p.currToken.Value)
 		return nil, err
 	}
 
@@ -422,8 +647,11 @@ func (p *Parser) parseReal(terminatedTokens []token.TokenType) (ast.IExpression,
 	node := &ast.NumFloat{Token: p.currToken}
 
 	value, err := strconv.ParseFloat(p.currToken.Value, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, p.parseError(diag.FloatLiteralOutOfRange, p.currToken.Value)
+	}
 	if err != nil {
-		err := p.parseError("could not parse %q as float", p.currToken.Value)
+		err := p.parseError(diag.InvalidFloatLiteral, p.currToken.Value)
 		return nil, err
 	}
 
@@ -569,8 +797,188 @@ func (p *Parser) parseIfStatement() (ast.IExpression, error) {
 	return stmt, err
 }
 
+func (p *Parser) parseAssertStatement() (*ast.Assert, error) {
+	stmt := &ast.Assert{Token: p.currToken}
+
+	var err error
+	if err = p.read(); err != nil {
+		return nil, err
+	}
+
+	conditionStart := p.currToken.Pos
+	stmt.Condition, err = p.parseExpression(Lowest, []token.TokenType{token.Comma, token.EOL})
+	if err != nil {
+		return nil, err
+	}
+	stmt.ConditionText = strings.TrimSpace(p.l.Source(conditionStart, p.nextToken.Pos))
+
+	if err = p.read(); err != nil {
+		return nil, err
+	}
+	if p.currToken.Type == token.Comma {
+		if err = p.requireToken(token.String); err != nil {
+			return nil, err
+		}
+		stmt.Message = p.currToken.Value
+		if err = p.read(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = p.getExpectedToken(token.EOL); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseTryStatement() (*ast.TryStatement, error) {
+	stmt := &ast.TryStatement{Token: p.currToken}
+
+	var err error
+	if err = p.requireTokenSequence([]token.TokenType{token.LBrace, token.EOL}); err != nil {
+		return nil, err
+	}
+	if err = p.read(); err != nil {
+		return nil, err
+	}
+
+	statements, err := p.parseBlockOfStatements(token.GetTokenTypes(token.RBrace))
+	if err != nil {
+		return nil, err
+	}
+	stmt.Body = &ast.StatementsBlock{Statements: statements}
+
+	if err = p.requireToken(token.Recover); err != nil {
+		return nil, err
+	}
+	if err = p.read(); err != nil {
+		return nil, err
+	}
+
+	if p.currToken.Type == token.Ident {
+		stmt.ErrVar = &ast.Identifier{Token: p.currToken, Value: p.currToken.Value}
+		if err = p.read(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = p.getExpectedToken(token.LBrace); err != nil {
+		return nil, err
+	}
+	if err = p.requireToken(token.EOL); err != nil {
+		return nil, err
+	}
+	if err = p.read(); err != nil {
+		return nil, err
+	}
+
+	statements, err = p.parseBlockOfStatements(token.GetTokenTypes(token.RBrace))
+	stmt.RecoverBranch = &ast.StatementsBlock{Statements: statements}
+
+	return stmt, err
+}
+
+func (p *Parser) parseIfExpression(terminatedTokens []token.TokenType) (ast.IExpression, error) {
+	node := &ast.IfExpression{Token: p.currToken}
+
+	var err error
+	if err = p.read(); err != nil {
+		return nil, err
+	}
+
+	node.Condition, err = p.parseExpression(Lowest, token.GetTokenTypes(token.LBrace))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.requireToken(token.LBrace); err != nil {
+		return nil, err
+	}
+	if node.PositiveBranch, err = p.parseIfExpressionBranch(); err != nil {
+		return nil, err
+	}
+
+	if err = p.requireToken(token.Else); err != nil {
+		return nil, err
+	}
+	if err = p.read(); err != nil {
+		return nil, err
+	}
+
+	// else if chain
+	if p.currToken.Type == token.If {
+		node.ElseBranch, err = p.parseIfExpression(terminatedTokens)
+		return node, err
+	}
+
+	if _, err = p.getExpectedToken(token.LBrace); err != nil {
+		return nil, err
+	}
+	node.ElseBranch, err = p.parseIfExpressionBranch()
+
+	return node, err
+}
+
+func (p *Parser) parseIfExpressionBranch() (ast.IExpression, error) {
+	p.nestingLevel++
+	defer func() { p.nestingLevel-- }()
+	if err := p.read(); err != nil {
+		return nil, err
+	}
+
+	expression, err := p.parseExpression(Lowest, token.GetTokenTypes(token.RBrace))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.requireToken(token.RBrace); err != nil {
+		return nil, err
+	}
+
+	return expression, nil
+}
+
+func (p *Parser) parseTypeDefinition() (*ast.TypeDefinition, error) {
+	node := &ast.TypeDefinition{Token: p.currToken}
+
+	if err := p.read(); err != nil {
+		return nil, err
+	}
+	node.Name = p.currToken.Value
+
+	if err := p.read(); err != nil {
+		return nil, err
+	}
+	if p.currToken.Type == token.Assignment {
+		node.IsAlias = true
+		if err := p.read(); err != nil {
+			return nil, err
+		}
+	}
+
+	arrayTypePrefix := ""
+	if p.currToken.Type == token.LBracket {
+		if err := p.requireToken(token.RBracket); err != nil {
+			return nil, err
+		}
+		arrayTypePrefix = "[]"
+		if err := p.read(); err != nil {
+			return nil, err
+		}
+	}
+
+	typeToken, err := p.getExpectedTokens([]token.TokenType{token.Type, token.Ident})
+	if err != nil {
+		return nil, err
+	}
+	node.Underlying = arrayTypePrefix + typeToken.Value
+
+	return node, nil
+}
+
 func (p *Parser) parseStructDefinition() (ast.IExpression, error) {
-	node := &ast.StructDefinition{Token: p.currToken}
+	node := &ast.StructDefinition{Token: p.currToken, Doc: p.currToken.Doc}
 
 	if err := p.read(); err != nil {
 		return nil, err
@@ -594,21 +1002,28 @@ func (p *Parser) parseStructDefinition() (ast.IExpression, error) {
 		return nil, err
 	}
 	if len(fields) == 0 {
-		return nil, p.parseError("Struct should contain at least 1 field")
+		return nil, p.parseError(diag.EmptyStructDefinition)
 	}
 
-	fieldsMap := make(map[string]*ast.VarAndType)
+	defined := make(map[string]bool)
 	for _, field := range fields {
-		fieldsMap[field.Var.Value] = field
+		if defined[field.Var.Value] {
+			return nil, p.parseError(diag.DuplicateStructField, field.Var.Value)
+		}
+		defined[field.Var.Value] = true
 	}
 
-	node.Fields = fieldsMap
+	node.Fields = fields
 
 	return node, nil
 }
 
 func (p *Parser) parseFunction(terminatedTokens []token.TokenType) (ast.IExpression, error) {
 	function := &ast.Function{Token: p.currToken}
+	// line breaks are significant inside function body even if function literal is enclosed in brackets
+	nestingLevel := p.nestingLevel
+	p.nestingLevel = 0
+	defer func() { p.nestingLevel = nestingLevel }()
 
 	err := p.read()
 	if err != nil {
@@ -709,6 +1124,8 @@ func (p *Parser) parseFunctionCall(function ast.IExpression, terminatedTokens []
 		Token:    p.currToken,
 		Function: function,
 	}
+	p.nestingLevel++
+	defer func() { p.nestingLevel-- }()
 	var err error
 	if err = p.read(); err != nil {
 		return nil, err
@@ -743,6 +1160,8 @@ func (p *Parser) parseExpressions(closeTokens []token.TokenType) ([]ast.IExpress
 }
 
 func (p *Parser) parseGroupedExpression(terminatedTokens []token.TokenType) (ast.IExpression, error) {
+	p.nestingLevel++
+	defer func() { p.nestingLevel-- }()
 	err := p.read()
 	if err != nil {
 		return nil, err
@@ -795,6 +1214,8 @@ func (p *Parser) parseArray(terminatedTokens []token.TokenType) (ast.IExpression
 
 	var elementExpressions []ast.IExpression
 	if p.currToken.Type == token.LBrace {
+		p.nestingLevel++
+		defer func() { p.nestingLevel-- }()
 		if err = p.read(); err != nil {
 			return nil, err
 		}
@@ -814,6 +1235,8 @@ func (p *Parser) parseArrayIndexCall(array ast.IExpression, terminatedTokens []t
 		Token: p.currToken,
 		Left:  array,
 	}
+	p.nestingLevel++
+	defer func() { p.nestingLevel-- }()
 
 	var err error
 	if err = p.read(); err != nil {
@@ -839,12 +1262,14 @@ func (p *Parser) parseStructExpression(
 ) (ast.IExpression, error) {
 	ident, ok := expr.(*ast.Identifier)
 	if !ok {
-		return nil, p.parseError("Struct operator should only on identifiers, but '%T'", expr)
+		return nil, p.parseError(diag.StructLiteralWithoutName, expr)
 	}
 	node := &ast.Struct{
 		Token: p.currToken,
 		Ident: ident,
 	}
+	p.nestingLevel++
+	defer func() { p.nestingLevel-- }()
 	if err := p.read(); err != nil {
 		return nil, err
 	}
@@ -871,6 +1296,7 @@ func (p *Parser) parseStructFieldCall(expr ast.IExpression, terminatedTokens []t
 	node := &ast.StructFieldCall{
 		Token:      p.currToken,
 		StructExpr: expr,
+		IsSafe:     p.currToken.Type == token.SafeDot,
 	}
 	if err := p.read(); err != nil {
 		return nil, err
@@ -886,7 +1312,7 @@ func (p *Parser) parseStructFieldCall(expr ast.IExpression, terminatedTokens []t
 }
 
 func (p *Parser) parseEnumDefinition() (ast.IExpression, error) {
-	node := &ast.EnumDefinition{Token: p.currToken}
+	node := &ast.EnumDefinition{Token: p.currToken, Doc: p.currToken.Doc}
 
 	if err := p.read(); err != nil {
 		return nil, err
@@ -965,8 +1391,7 @@ func (p *Parser) curPrecedence() int {
 
 func (p *Parser) getExpectedToken(tokenType token.TokenType) (token.Token, error) {
 	if p.currToken.Type != tokenType {
-		err := p.parseError("expected token to be '%s', got '%s' instead",
-			tokenType, p.currToken.Type)
+		err := p.parseError(diag.UnexpectedToken, tokenType, p.currToken.Type)
 		return token.Token{}, err
 	}
 	return p.currToken, nil
@@ -981,8 +1406,7 @@ func (p *Parser) getExpectedTokens(tokenTypes []token.TokenType) (token.Token, e
 			return p.currToken, nil
 		}
 	}
-	err := p.parseError("expected token to be one of (%s), got '%s' instead",
-		token.GetTokensString(tokenTypes), p.currToken.Type)
+	err := p.parseError(diag.UnexpectedTokenOneOf, token.GetTokensString(tokenTypes), p.currToken.Type)
 	return token.Token{}, err
 }
 
@@ -1031,7 +1455,10 @@ func (p *Parser) registerBinExprFunction(tokenType token.TokenType, fn binExprFu
 	p.binExprFunctions[tokenType] = fn
 }
 
-func (p *Parser) parseError(format string, args ...interface{}) error {
-	msg := fmt.Sprintf(format, args...)
-	return errors.New(fmt.Sprintf("%s\nline:%d, pos %d", msg, p.currToken.Line, p.currToken.Col))
+func (p *Parser) parseError(code diag.Code, args ...interface{}) error {
+	return p.parseErrorAt(p.currToken, code, args...)
+}
+
+func (p *Parser) parseErrorAt(t token.Token, code diag.Code, args ...interface{}) error {
+	return diag.New(diag.PhaseParse, code, t, args...)
 }
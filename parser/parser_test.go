@@ -2,11 +2,15 @@ package parser
 
 import (
 	"github.com/justclimber/marslang/ast"
+	"github.com/justclimber/marslang/diag"
 	"github.com/justclimber/marslang/lexer"
+	"github.com/justclimber/marslang/token"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -207,3 +211,513 @@ func TestInvalidExpr(t *testing.T) {
 	_, err = p.Parse()
 	require.NotNil(t, err)
 }
+
+func TestParseStructDefinitionKeepsFieldsOrder(t *testing.T) {
+	input := `struct point {
+   float y
+   float x
+}
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	require.Len(t, astProgram.Statements, 1)
+	require.IsType(t, &ast.StructDefinition{}, astProgram.Statements[0])
+	def, _ := astProgram.Statements[0].(*ast.StructDefinition)
+	require.Len(t, def.Fields, 2)
+	assert.Equal(t, "y", def.Fields[0].Var.Value)
+	assert.Equal(t, "x", def.Fields[1].Var.Value)
+}
+
+func TestStructDuplicateFieldNegative(t *testing.T) {
+	input := `struct point {
+   float x
+   int x
+}
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+	_, err = p.Parse()
+	require.NotNil(t, err)
+}
+
+func TestParseConstFolding(t *testing.T) {
+	input := `const A = 2
+const B = (A + 3) * 10
+const C = -1.5 * 2.
+const D = B > 40 && !false
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+	require.Len(t, astProgram.Statements, 4)
+
+	expected := []ast.IExpression{
+		&ast.NumInt{Value: 2},
+		&ast.NumInt{Value: 50},
+		&ast.NumFloat{Value: -3.},
+		&ast.Boolean{Value: true},
+	}
+	for i, stmt := range astProgram.Statements {
+		require.IsType(t, &ast.ConstDefinition{}, stmt, "%d statement", i)
+		constStmt, _ := stmt.(*ast.ConstDefinition)
+		require.IsType(t, expected[i], constStmt.Value, "%d statement", i)
+		switch value := constStmt.Value.(type) {
+		case *ast.NumInt:
+			assert.Equal(t, expected[i].(*ast.NumInt).Value, value.Value, "%d statement", i)
+		case *ast.NumFloat:
+			assert.Equal(t, expected[i].(*ast.NumFloat).Value, value.Value, "%d statement", i)
+		case *ast.Boolean:
+			assert.Equal(t, expected[i].(*ast.Boolean).Value, value.Value, "%d statement", i)
+		}
+	}
+}
+
+func TestConstNegative(t *testing.T) {
+	tests := map[string]string{
+		"assignment to const": `const A = 2
+A = 3
+`,
+		"const redefinition": `const A = 2
+const A = 3
+`,
+		"not a constant expression": `a = 2
+const A = a + 1
+`,
+		"division by zero": `const A = 2 / 0
+`,
+		"const in block": `if true {
+   const A = 1
+}
+`,
+	}
+	for name, input := range tests {
+		l := lexer.New(input)
+		p, err := New(l)
+		require.Nil(t, err, name)
+		_, err = p.Parse()
+		require.NotNil(t, err, name)
+	}
+}
+
+func TestConstIntOverflow(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		col   int
+	}{
+		"plus":        {"const X = 9223372036854775807 + 1\n", 31},
+		"minus":       {"const X = -9223372036854775807 - 2\n", 32},
+		"multiply":    {"const X = 4611686018427387904 * 2\n", 31},
+		"divide":      {"const M = -9223372036854775807 - 1\nconst X = M / -1\n", 13},
+		"unary minus": {"const M = -9223372036854775807 - 1\nconst X = -M\n", 11},
+	}
+	for name, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		require.Nil(t, err, name)
+		_, err = p.Parse()
+		require.NotNil(t, err, name)
+		errs, _ := err.(diag.List)
+		require.Len(t, errs, 1, name)
+		assert.Equal(t, diag.IntOverflow, errs[0].Code, name)
+		assert.Equal(t, strings.Count(tt.input, "\n"), errs[0].Line, name)
+		assert.Equal(t, tt.col, errs[0].Col, name)
+	}
+}
+
+func TestParseIfExpression(t *testing.T) {
+	input := `a = if b > 0 { 1 } else if b < 0 { -1 } else { 0 }
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+
+	require.Len(t, astProgram.Statements, 1)
+	assignStmt, _ := astProgram.Statements[0].(*ast.Assignment)
+	require.IsType(t, &ast.IfExpression{}, assignStmt.Value)
+
+	ifExpr, _ := assignStmt.Value.(*ast.IfExpression)
+	assert.IsType(t, &ast.BinExpression{}, ifExpr.Condition)
+	assert.IsType(t, &ast.NumInt{}, ifExpr.PositiveBranch)
+	require.IsType(t, &ast.IfExpression{}, ifExpr.ElseBranch)
+
+	elseIfExpr, _ := ifExpr.ElseBranch.(*ast.IfExpression)
+	assert.IsType(t, &ast.UnaryExpression{}, elseIfExpr.PositiveBranch)
+	assert.IsType(t, &ast.NumInt{}, elseIfExpr.ElseBranch)
+}
+
+func TestParseTypeDefinition(t *testing.T) {
+	input := `type Angle float
+type Points = []point
+type = 5
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+	require.Len(t, astProgram.Statements, 3)
+
+	require.IsType(t, &ast.TypeDefinition{}, astProgram.Statements[0])
+	angle, _ := astProgram.Statements[0].(*ast.TypeDefinition)
+	assert.Equal(t, "Angle", angle.Name)
+	assert.Equal(t, "float", angle.Underlying)
+	assert.False(t, angle.IsAlias)
+
+	require.IsType(t, &ast.TypeDefinition{}, astProgram.Statements[1])
+	points, _ := astProgram.Statements[1].(*ast.TypeDefinition)
+	assert.Equal(t, "Points", points.Name)
+	assert.Equal(t, "[]point", points.Underlying)
+	assert.True(t, points.IsAlias)
+
+	assert.IsType(t, &ast.Assignment{}, astProgram.Statements[2])
+}
+
+func TestParseMultiLineExpressions(t *testing.T) {
+	input := `a = foo(
+   1,
+   2,
+)
+b = []int{1,
+   2,}
+c = point{x = 1.,
+   y = 2.
+}
+d = (1
+   + 2)
+e = 3
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+	require.Len(t, astProgram.Statements, 5)
+
+	assignA, _ := astProgram.Statements[0].(*ast.Assignment)
+	require.IsType(t, &ast.FunctionCall{}, assignA.Value)
+	assert.Len(t, assignA.Value.(*ast.FunctionCall).Arguments, 2)
+
+	assignB, _ := astProgram.Statements[1].(*ast.Assignment)
+	require.IsType(t, &ast.Array{}, assignB.Value)
+	assert.Len(t, assignB.Value.(*ast.Array).Elements, 2)
+
+	assignC, _ := astProgram.Statements[2].(*ast.Assignment)
+	require.IsType(t, &ast.Struct{}, assignC.Value)
+	assert.Len(t, assignC.Value.(*ast.Struct).Fields, 2)
+
+	assignD, _ := astProgram.Statements[3].(*ast.Assignment)
+	assert.IsType(t, &ast.BinExpression{}, assignD.Value)
+
+	assignE, _ := astProgram.Statements[4].(*ast.Assignment)
+	assert.Equal(t, "e", assignE.Left.Value)
+}
+
+func TestNewLineOutsideBracketsNegative(t *testing.T) {
+	input := `a = 1
+   + 2
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+	_, err = p.Parse()
+	require.NotNil(t, err)
+}
+
+func TestBinExpressionLeftAssociativity(t *testing.T) {
+	l := lexer.New("a = 1 - 2 * 3 - 4\n")
+	p, err := New(l)
+	require.Nil(t, err)
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+	require.Len(t, astProgram.Statements, 1)
+
+	// ((1 - (2 * 3)) - 4)
+	assign, _ := astProgram.Statements[0].(*ast.Assignment)
+	require.IsType(t, &ast.BinExpression{}, assign.Value)
+	outer := assign.Value.(*ast.BinExpression)
+	assert.Equal(t, token.Minus, outer.Operator)
+	assert.Equal(t, int64(4), outer.Right.(*ast.NumInt).Value)
+
+	require.IsType(t, &ast.BinExpression{}, outer.Left)
+	inner := outer.Left.(*ast.BinExpression)
+	assert.Equal(t, token.Minus, inner.Operator)
+	assert.Equal(t, int64(1), inner.Left.(*ast.NumInt).Value)
+
+	require.IsType(t, &ast.BinExpression{}, inner.Right)
+	assert.Equal(t, token.Asterisk, inner.Right.(*ast.BinExpression).Operator)
+}
+
+func TestParseDocComments(t *testing.T) {
+	input := `/// Point on the map
+struct point {
+   float x
+}
+// Directions of move
+enum dir {up, down}
+/// Sum of two ints
+sum = fn(int x, int y) int {
+   return x + y
+}
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+	require.Len(t, astProgram.Statements, 3)
+
+	require.IsType(t, &ast.StructDefinition{}, astProgram.Statements[0])
+	assert.Equal(t, "Point on the map", astProgram.Statements[0].(*ast.StructDefinition).Doc)
+
+	require.IsType(t, &ast.EnumDefinition{}, astProgram.Statements[1])
+	assert.Equal(t, "Directions of move", astProgram.Statements[1].(*ast.EnumDefinition).Doc)
+
+	assignStmt, _ := astProgram.Statements[2].(*ast.Assignment)
+	require.IsType(t, &ast.Function{}, assignStmt.Value)
+	assert.Equal(t, "Sum of two ints", assignStmt.Value.(*ast.Function).Doc)
+}
+
+func TestParseNumberLiterals(t *testing.T) {
+	input := `a = 0xFF + 0b1010 + 1_000
+b = .5 + 1e-3
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.Nil(t, err)
+	require.Len(t, astProgram.Statements, 2)
+
+	assignA, _ := astProgram.Statements[0].(*ast.Assignment)
+	sumA, _ := assignA.Value.(*ast.BinExpression)
+	require.NotNil(t, sumA)
+	assert.Equal(t, int64(1000), sumA.Right.(*ast.NumInt).Value)
+	innerSum, _ := sumA.Left.(*ast.BinExpression)
+	require.NotNil(t, innerSum)
+	assert.Equal(t, int64(255), innerSum.Left.(*ast.NumInt).Value)
+	assert.Equal(t, int64(10), innerSum.Right.(*ast.NumInt).Value)
+
+	assignB, _ := astProgram.Statements[1].(*ast.Assignment)
+	sumB, _ := assignB.Value.(*ast.BinExpression)
+	require.NotNil(t, sumB)
+	assert.Equal(t, .5, sumB.Left.(*ast.NumFloat).Value)
+	assert.Equal(t, 1e-3, sumB.Right.(*ast.NumFloat).Value)
+}
+
+func TestNumberLiteralOverflowNegative(t *testing.T) {
+	inputs := []string{
+		"a = 9223372036854775808\n",
+		"a = 0xFFFFFFFFFFFFFFFFF\n",
+		"a = 1e400\n",
+	}
+	for _, input := range inputs {
+		l := lexer.New(input)
+		p, err := New(l)
+		require.Nil(t, err, input)
+		_, err = p.Parse()
+		require.NotNil(t, err, input)
+		assert.Contains(t, err.Error(), "line:1, pos 5", input)
+	}
+}
+
+func TestParseWithoutTrailingNewLine(t *testing.T) {
+	inputs := []string{"", "a = 5", "a = 5 // comment", "if true {\r\n\tb = 1\r\n}"}
+	for _, input := range inputs {
+		l := lexer.New(input)
+		p, err := New(l)
+		require.Nil(t, err, "%q", input)
+		_, err = p.Parse()
+		require.Nil(t, err, "%q", input)
+	}
+}
+
+func TestParseErrorIsStructured(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		phase diag.Phase
+		code  diag.Code
+		line  int
+		col   int
+	}{
+		"parse": {"a = 1\nb = 5 + 10 )\n", diag.PhaseParse, diag.UnexpectedToken, 2, 12},
+		"const": {"const A = 1\nA = 2\n", diag.PhaseParse, diag.AssignmentToConst, 2, 1},
+		"lex":   {"a = 1\nb = 2 & 3\n", diag.PhaseLex, diag.SingleAmpersand, 2, 7},
+	}
+	for name, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		require.Nil(t, err, name)
+		_, err = p.Parse()
+		require.NotNil(t, err, name)
+
+		var diagErr *diag.Error
+		require.True(t, errors.As(err, &diagErr), name)
+		assert.Equal(t, tt.phase, diagErr.Phase, name)
+		assert.Equal(t, tt.code, diagErr.Code, name)
+		assert.Equal(t, tt.line, diagErr.Line, name)
+		assert.Equal(t, tt.col, diagErr.Col, name)
+	}
+}
+
+func TestParseMultipleErrors(t *testing.T) {
+	input := `a = 5 +
+b = 2 & 3
+if a > {
+   x = 1
+}
+f = fn(int x) int {
+   y = = 2
+   return x
+}
+}
+c = @
+d = 4
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.NotNil(t, err)
+	require.IsType(t, diag.List{}, err)
+
+	errs, _ := err.(diag.List)
+	lines := make([]int, 0, len(errs))
+	for _, e := range errs {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []int{1, 2, 3, 7, 10, 11}, lines)
+	assert.Equal(t, diag.PhaseLex, errs[1].Phase)
+
+	require.Len(t, astProgram.Statements, 2)
+	assignF, _ := astProgram.Statements[0].(*ast.Assignment)
+	assert.Equal(t, "f", assignF.Left.Value)
+	require.IsType(t, &ast.Function{}, assignF.Value)
+	assert.Len(t, assignF.Value.(*ast.Function).StatementsBlock.Statements, 1)
+	assignD, _ := astProgram.Statements[1].(*ast.Assignment)
+	assert.Equal(t, "d", assignD.Left.Value)
+}
+
+func TestParseMaxErrors(t *testing.T) {
+	input := `a = 1 +
+b = 2 +
+c = 3 +
+d = 4 +
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+	p.SetMaxErrors(2)
+
+	_, err = p.Parse()
+	require.NotNil(t, err)
+	assert.Len(t, err.(diag.List), 2)
+}
+
+func TestParseMaxErrorsUnlimited(t *testing.T) {
+	for _, maxErrors := range []int{0, -1} {
+		l := lexer.New("a = 1 +\nb = 2 +\nc = 3\n")
+		p, err := New(l)
+		require.Nil(t, err)
+		p.SetMaxErrors(maxErrors)
+
+		astProgram, err := p.Parse()
+		require.NotNil(t, err, "max errors %d", maxErrors)
+		assert.Len(t, err.(diag.List), 2, "max errors %d", maxErrors)
+		assert.Len(t, astProgram.Statements, 1, "max errors %d", maxErrors)
+	}
+}
+
+func TestParseLexerErrorAtStart(t *testing.T) {
+	l := lexer.New("@ a = 1\nb = 2\n")
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.NotNil(t, err)
+	errs, ok := err.(diag.List)
+	require.True(t, ok)
+	require.Len(t, errs, 1)
+	assert.Equal(t, diag.PhaseLex, errs[0].Phase)
+	assert.Equal(t, 1, errs[0].Line)
+	require.Len(t, astProgram.Statements, 2)
+}
+
+func TestParseErrorsLocale(t *testing.T) {
+	input := `a = 5 +
+b = 2 & 3
+`
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+	p.SetLocale(diag.LocaleRu)
+
+	_, err = p.Parse()
+	require.NotNil(t, err)
+	errs, _ := err.(diag.List)
+	require.Len(t, errs, 2)
+	assert.Equal(t, "Выражение не может начинаться с токена EOL", errs[0].Message)
+	assert.Equal(t, "Одиночный `&`. Возможно, имелось в виду '&&'?", errs[1].Message)
+	assert.Equal(t, diag.SingleAmpersand, errs[1].Code)
+}
+
+func TestParseNestingTooDeep(t *testing.T) {
+	depth := MaxNestingDepth * 2
+	input := "a = " + strings.Repeat("(", depth) + "1" + strings.Repeat(")", depth) + "\n" +
+		strings.Repeat("if true {\n", depth) + strings.Repeat("}\n", depth) +
+		"b = 2\n"
+	l := lexer.New(input)
+	p, err := New(l)
+	require.Nil(t, err)
+
+	astProgram, err := p.Parse()
+	require.NotNil(t, err)
+	var diagErr *diag.Error
+	require.True(t, errors.As(err, &diagErr))
+	assert.Equal(t, diag.NestingTooDeep, diagErr.Code)
+	assert.Len(t, err.(diag.List), 2)
+	require.Len(t, astProgram.Statements, 2)
+	assert.Equal(t, "b", astProgram.Statements[1].(*ast.Assignment).Left.Value)
+}
+
+func TestParseUnterminatedBlock(t *testing.T) {
+	// every unclosed block is reported once
+	tests := map[string]int{
+		"if true {\n a = 1\n":            1,
+		"f = fn() int {\n":               1,
+		"try {":                          1,
+		"if true {\n   f = fn() int {\n": 2,
+	}
+	for input, errorsCount := range tests {
+		l := lexer.New(input)
+		p, err := New(l)
+		require.Nil(t, err, "%q", input)
+		p.SetMaxErrors(0)
+
+		_, err = p.Parse()
+		require.NotNil(t, err, "%q", input)
+		errs, ok := err.(diag.List)
+		require.True(t, ok, "%q", input)
+		require.Len(t, errs, errorsCount, "%q", input)
+		for _, e := range errs {
+			assert.Equal(t, diag.UnexpectedToken, e.Code, "%q", input)
+			assert.Equal(t, "expected token to be '}', got 'EOF' instead", e.Message, "%q", input)
+		}
+	}
+}
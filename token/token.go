@@ -8,6 +8,8 @@ import (
 const (
 	EOL = "EOL"
 	EOF = "EOF"
+	// token lexer failed to read, error is already reported
+	Illegal = "ILLEGAL"
 
 	Assignment = "="
 	Comma      = ","
@@ -15,6 +17,9 @@ const (
 	Colon      = ":"
 	Question   = "?"
 
+	NullCoalesce = "??"
+	SafeDot      = "?."
+
 	// arithmetical operators
 	Plus     = "+"
 	Minus    = "-"
@@ -32,6 +37,7 @@ const (
 
 	NumInt   = "int_num"
 	NumFloat = "float_num"
+	String   = "string"
 
 	LParen   = "("
 	RParen   = ")"
@@ -54,6 +60,10 @@ const (
 	Switch   = "switch"
 	Case     = "case"
 	Default  = "default"
+	Const    = "const"
+	Try      = "try"
+	Recover  = "recover"
+	Assert   = "assert"
 
 	// type hints
 	Type = "type"
@@ -67,6 +77,11 @@ type Token struct {
 	Line  int
 	Col   int
 	Pos   int
+	// position right after the last char of the token
+	EndLine int
+	EndCol  int
+	// text of comments placed on separate lines directly above the token
+	Doc string
 }
 
 var keywords = map[string]TokenType{
@@ -84,6 +99,10 @@ var keywords = map[string]TokenType{
 	"switch":  Switch,
 	"case":    Case,
 	"default": Default,
+	"const":   Const,
+	"try":     Try,
+	"recover": Recover,
+	"assert":  Assert,
 }
 
 func LookupIdent(ident string) TokenType {